@@ -0,0 +1,99 @@
+package vaultrole
+
+import (
+	"time"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/vaultrole/key"
+)
+
+const (
+	AuditOperationCreate AuditOperation = "create"
+	AuditOperationDelete AuditOperation = "delete"
+	AuditOperationUpdate AuditOperation = "update"
+)
+
+// AuditOperation is the kind of change an AuditEvent records.
+type AuditOperation string
+
+// AuditEvent records a successful change of a role.
+type AuditEvent struct {
+	// Actor is the configured Config.AuditActor.
+	Actor     string         `json:"actor,omitempty"`
+	ClusterID string         `json:"clusterID"`
+	Namespace string         `json:"namespace,omitempty"`
+	Operation AuditOperation `json:"operation"`
+	// Previous is the role before the change. It is nil for created roles.
+	Previous *Role `json:"previous,omitempty"`
+	// Current is the role after the change. It is nil for deleted roles.
+	Current   *Role     `json:"current,omitempty"`
+	RoleName  string    `json:"roleName"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// AuditSink receives an AuditEvent for every successful change of a role. See
+// package auditsink for implementations.
+type AuditSink interface {
+	Emit(event AuditEvent) error
+}
+
+// audit emits an event for the given successful write to the configured sink.
+// Failing to emit the event does not fail the operation, since the role has
+// already been changed, so failures are logged instead.
+func (r *VaultRole) audit(operation AuditOperation, config writeConfig, previous *Role) {
+	current := r.roleFromWriteConfig(config)
+	r.emit(operation, config.Namespace, config.ID, config.Organizations, previous, &current)
+}
+
+// emit emits an event for the given successful change to the configured sink.
+func (r *VaultRole) emit(operation AuditOperation, namespace string, ID string, organizations []string, previous *Role, current *Role) {
+	if r.auditSink == nil || r.dryRun {
+		return
+	}
+
+	e := AuditEvent{
+		Actor:     r.auditActor,
+		ClusterID: ID,
+		Namespace: namespace,
+		Operation: operation,
+		Previous:  previous,
+		Current:   current,
+		RoleName:  key.RoleName(ID, organizations),
+		Timestamp: time.Now().UTC(),
+	}
+
+	err := r.auditSink.Emit(e)
+	if err != nil {
+		r.logger.Log("level", "error", "message", "failed to emit audit event", "roleName", e.RoleName, "stack", microerror.JSON(err))
+	}
+}
+
+// previousRole reads the current role before it gets changed, in case audit
+// events are emitted.
+func (r *VaultRole) previousRole(namespace string, ID string, organizations []string) (*Role, error) {
+	if r.auditSink == nil || r.dryRun {
+		return nil, nil
+	}
+
+	role, err := r.readRole(namespace, ID, key.ReadRolePath(ID, organizations))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return &role, nil
+}
+
+// roleFromWriteConfig returns the role as it results from writing the given
+// config, by decoding the data written to Vault. The config must have been
+// validated and planned.
+func (r *VaultRole) roleFromWriteConfig(config writeConfig) Role {
+	// Planning the config already succeeded, so computing the common name and
+	// encoding and decoding the payload cannot fail.
+	commonName, _ := r.commonName(config.CommonNameFormat, config.CommonNameValues, config.ID)
+	data, _ := roleSchema.encode(writePayload(config, commonName))
+	role, _ := roleSchema.decode(data)
+
+	role.ID = config.ID
+	return role
+}
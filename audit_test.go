@@ -0,0 +1,74 @@
+package vaultrole_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/vaultrole"
+	"github.com/giantswarm/vaultrole/vaultroletest"
+)
+
+type testAuditSink struct {
+	events []vaultrole.AuditEvent
+}
+
+func (s *testAuditSink) Emit(event vaultrole.AuditEvent) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func Test_VaultRole_Audit(t *testing.T) {
+	s := vaultroletest.NewServer()
+	defer s.Close()
+	s.Mount("", "pki-al9qy")
+
+	sink := &testAuditSink{}
+
+	r, err := s.NewVaultRole(vaultrole.Config{
+		AuditSink:  sink,
+		AuditActor: "cert-operator",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Create(vaultrole.CreateConfig{ID: "al9qy", AltNames: []string{"kubernetes"}, TTL: "1h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Update(vaultrole.UpdateConfig{ID: "al9qy", AltNames: []string{"kubernetes"}, TTL: "2h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Create(vaultrole.CreateConfig{ID: "al9qy", TTL: "1h"})
+	if !vaultrole.IsAlreadyExists(err) {
+		t.Fatalf("expected already exists error got %#v", err)
+	}
+
+	if len(sink.events) != 2 {
+		t.Fatalf("expected 2 events got %d", len(sink.events))
+	}
+
+	{
+		e := sink.events[0]
+		if e.Operation != vaultrole.AuditOperationCreate || e.Actor != "cert-operator" || e.RoleName != "role-al9qy" || e.Previous != nil {
+			t.Fatalf("unexpected create event %#v", e)
+		}
+	}
+
+	{
+		e := sink.events[1]
+		if e.Operation != vaultrole.AuditOperationUpdate || e.Previous == nil || e.Current == nil {
+			t.Fatalf("unexpected update event %#v", e)
+		}
+
+		expectedPrevious := vaultrole.Role{AltNames: []string{"kubernetes"}, CommonName: "al9qy.g8s.gigantic.io", ID: "al9qy", Organizations: []string{}, TTL: time.Hour}
+		if !reflect.DeepEqual(*e.Previous, expectedPrevious) {
+			t.Fatalf("Previous == %#v, want %#v", *e.Previous, expectedPrevious)
+		}
+		if e.Current.TTL != 2*time.Hour {
+			t.Fatalf("Current.TTL == %v, want %v", e.Current.TTL, 2*time.Hour)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package auditsink
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/vaultrole"
+)
+
+type JSONLinesConfig struct {
+	Writer io.Writer
+}
+
+// JSONLines writes every audit event as a single line of JSON to the
+// configured writer. JSONLines is safe for concurrent use.
+type JSONLines struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+}
+
+func NewJSONLines(config JSONLinesConfig) (*JSONLines, error) {
+	if config.Writer == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Writer must not be empty")
+	}
+
+	j := &JSONLines{
+		encoder: json.NewEncoder(config.Writer),
+	}
+
+	return j, nil
+}
+
+func (j *JSONLines) Emit(event vaultrole.AuditEvent) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	err := j.encoder.Encode(event)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
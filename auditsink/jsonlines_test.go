@@ -0,0 +1,51 @@
+package auditsink
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/vaultrole"
+)
+
+func Test_JSONLines_Emit(t *testing.T) {
+	var b bytes.Buffer
+
+	s, err := NewJSONLines(JSONLinesConfig{Writer: &b})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := []vaultrole.AuditEvent{
+		{
+			Actor:     "cert-operator",
+			ClusterID: "al9qy",
+			Operation: vaultrole.AuditOperationCreate,
+			Current:   &vaultrole.Role{ID: "al9qy", TTL: time.Hour},
+			RoleName:  "role-al9qy",
+			Timestamp: time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			ClusterID: "al9qy",
+			Operation: vaultrole.AuditOperationUpdate,
+			Previous:  &vaultrole.Role{ID: "al9qy", TTL: time.Hour},
+			Current:   &vaultrole.Role{ID: "al9qy", TTL: 2 * time.Hour},
+			RoleName:  "role-al9qy",
+			Timestamp: time.Date(2020, 4, 1, 13, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, e := range events {
+		err := s.Emit(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := `{"actor":"cert-operator","clusterID":"al9qy","operation":"create","current":{"AllowBareDomains":false,"AllowSubdomains":false,"AltNames":null,"ID":"al9qy","Organizations":null,"TTL":3600000000000},"roleName":"role-al9qy","timestamp":"2020-04-01T12:00:00Z"}
+{"clusterID":"al9qy","operation":"update","previous":{"AllowBareDomains":false,"AllowSubdomains":false,"AltNames":null,"ID":"al9qy","Organizations":null,"TTL":3600000000000},"current":{"AllowBareDomains":false,"AllowSubdomains":false,"AltNames":null,"ID":"al9qy","Organizations":null,"TTL":7200000000000},"roleName":"role-al9qy","timestamp":"2020-04-01T13:00:00Z"}
+`
+	if b.String() != expected {
+		t.Fatalf("expected %s got %s", expected, b.String())
+	}
+}
@@ -0,0 +1,59 @@
+// Package auditsink provides implementations of vaultrole.AuditSink.
+package auditsink
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/vaultrole"
+)
+
+type LoggerConfig struct {
+	Logger micrologger.Logger
+}
+
+// Logger emits audit events as log messages.
+type Logger struct {
+	logger micrologger.Logger
+}
+
+func NewLogger(config LoggerConfig) (*Logger, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	l := &Logger{
+		logger: config.Logger,
+	}
+
+	return l, nil
+}
+
+func (l *Logger) Emit(event vaultrole.AuditEvent) error {
+	previous, err := json.Marshal(event.Previous)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	current, err := json.Marshal(event.Current)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	l.logger.Log(
+		"level", "info",
+		"message", "Vault role changed",
+		"actor", event.Actor,
+		"clusterID", event.ClusterID,
+		"current", string(current),
+		"namespace", event.Namespace,
+		"operation", string(event.Operation),
+		"previous", string(previous),
+		"roleName", event.RoleName,
+		"timestamp", event.Timestamp.Format(time.RFC3339Nano),
+	)
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"fmt"
+
+	"github.com/giantswarm/microerror"
+	vaultclient "github.com/hashicorp/vault/api"
+)
+
+type AppRoleConfig struct {
+	// MountPath is the path the AppRole auth method is mounted at. Defaults to
+	// approle.
+	MountPath string
+	RoleID    string
+	SecretID  string
+}
+
+// AppRole authenticates using the AppRole auth method.
+type AppRole struct {
+	mountPath string
+	roleID    string
+	secretID  string
+}
+
+func NewAppRole(config AppRoleConfig) (*AppRole, error) {
+	if config.MountPath == "" {
+		config.MountPath = "approle"
+	}
+	if config.RoleID == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.RoleID must not be empty")
+	}
+
+	a := &AppRole{
+		mountPath: config.MountPath,
+		roleID:    config.RoleID,
+		secretID:  config.SecretID,
+	}
+
+	return a, nil
+}
+
+func (a *AppRole) Login(client *vaultclient.Client) (*vaultclient.Secret, error) {
+	v := map[string]interface{}{
+		"role_id": a.roleID,
+	}
+	if a.secretID != "" {
+		v["secret_id"] = a.secretID
+	}
+
+	secret, err := login(client, fmt.Sprintf("auth/%s/login", a.mountPath), v)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return secret, nil
+}
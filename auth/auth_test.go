@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	vaultclient "github.com/hashicorp/vault/api"
+)
+
+// newLoginServer returns a server responding to login requests at the given
+// path with the given token and recording the request body.
+func newLoginServer(t *testing.T, path string, token string, body *map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/"+path {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("X-Vault-Token") != "" {
+			t.Errorf("expected login request without token")
+		}
+
+		err := json.NewDecoder(r.Body).Decode(body)
+		if err != nil {
+			t.Error(err)
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"auth": map[string]interface{}{
+				"client_token":   token,
+				"lease_duration": 3600,
+				"renewable":      true,
+			},
+		})
+	}))
+}
+
+func newClient(t *testing.T, address string) *vaultclient.Client {
+	c, err := vaultclient.NewClient(&vaultclient.Config{Address: address})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetToken("expired")
+
+	return c
+}
+
+func Test_AppRole_Login(t *testing.T) {
+	var body map[string]interface{}
+	s := newLoginServer(t, "auth/approle/login", "approle-token", &body)
+	defer s.Close()
+
+	client := newClient(t, s.URL)
+
+	m, err := NewAppRole(AppRoleConfig{RoleID: "role", SecretID: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := New(Config{Logger: microloggertest.New(), Method: m, VaultClient: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = a.Login()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if client.Token() != "approle-token" {
+		t.Fatalf("expected token %#v got %#v", "approle-token", client.Token())
+	}
+	expected := map[string]interface{}{"role_id": "role", "secret_id": "secret"}
+	if !reflect.DeepEqual(body, expected) {
+		t.Fatalf("expected login data %#v got %#v", expected, body)
+	}
+}
+
+func Test_Kubernetes_Login(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vaultrole-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	jwtPath := filepath.Join(dir, "token")
+	err = ioutil.WriteFile(jwtPath, []byte("service-account-jwt\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body map[string]interface{}
+	s := newLoginServer(t, "auth/k8s/login", "kubernetes-token", &body)
+	defer s.Close()
+
+	client := newClient(t, s.URL)
+
+	m, err := NewKubernetes(KubernetesConfig{JWTPath: jwtPath, MountPath: "k8s", Role: "vaultrole"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := New(Config{Logger: microloggertest.New(), Method: m, VaultClient: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = a.Login()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if client.Token() != "kubernetes-token" {
+		t.Fatalf("expected token %#v got %#v", "kubernetes-token", client.Token())
+	}
+	expected := map[string]interface{}{"jwt": "service-account-jwt", "role": "vaultrole"}
+	if !reflect.DeepEqual(body, expected) {
+		t.Fatalf("expected login data %#v got %#v", expected, body)
+	}
+}
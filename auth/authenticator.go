@@ -0,0 +1,215 @@
+// Package auth provides client-side token lifecycle management for the Vault
+// client used by vaultrole. An Authenticator logs in using one of the supported
+// auth methods, keeps the resulting token alive in the background and
+// re-authenticates when the token cannot be renewed any longer.
+//
+// VaultRole logs in initially, runs the renewal loop until it is stopped and
+// logs in again when Vault denies a request.
+//
+//	a, err := auth.New(auth.Config{...})
+//	...
+//	r, err := vaultrole.New(vaultrole.Config{Authenticator: a, ...})
+//	...
+//	defer r.Stop()
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	vaultclient "github.com/hashicorp/vault/api"
+)
+
+type Config struct {
+	Logger      micrologger.Logger
+	Method      Method
+	VaultClient *vaultclient.Client
+
+	// RetryInterval is the time waited between failed login attempts of Run.
+	// Defaults to 10 seconds.
+	RetryInterval time.Duration
+}
+
+type Authenticator struct {
+	logger      micrologger.Logger
+	method      Method
+	vaultClient *vaultclient.Client
+
+	retryInterval time.Duration
+
+	mutex    sync.Mutex
+	loggedIn chan struct{}
+	secret   *vaultclient.Secret
+}
+
+func New(config Config) (*Authenticator, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+	if config.Method == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Method must not be empty")
+	}
+	if config.VaultClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.VaultClient must not be empty")
+	}
+
+	if config.RetryInterval < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.RetryInterval must not be negative")
+	}
+	if config.RetryInterval == 0 {
+		config.RetryInterval = 10 * time.Second
+	}
+
+	a := &Authenticator{
+		logger:      config.Logger,
+		method:      config.Method,
+		vaultClient: config.VaultClient,
+
+		retryInterval: config.RetryInterval,
+
+		loggedIn: make(chan struct{}, 1),
+	}
+
+	return a, nil
+}
+
+// Login authenticates using the configured auth method and sets the resulting
+// token on the configured Vault client. Login is safe for concurrent use and
+// is called by vaultrole.New and by vaultrole.VaultRole when Vault denies a
+// request.
+func (a *Authenticator) Login() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	secret, err := a.method.Login(a.vaultClient)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	if secret == nil || secret.Auth == nil || secret.Auth.ClientToken == "" {
+		return microerror.Maskf(loginFailedError, "login response does not contain a token")
+	}
+
+	a.vaultClient.SetToken(secret.Auth.ClientToken)
+	a.secret = secret
+
+	// Notify Run about the new token so it renews the new token instead of the
+	// old one.
+	select {
+	case a.loggedIn <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Run keeps the token of the configured Vault client alive until the given
+// context is done. Renewable tokens are renewed until they reach their maximum
+// TTL. Tokens which cannot be renewed any longer are replaced by logging in
+// again. Login must have been called successfully before calling Run.
+// vaultrole.New calls both when configured with an Authenticator.
+func (a *Authenticator) Run(ctx context.Context) {
+	for {
+		a.mutex.Lock()
+		secret := a.secret
+		a.mutex.Unlock()
+
+		// Drop notifications about logins which happened before the current
+		// secret has been picked up.
+		select {
+		case <-a.loggedIn:
+		default:
+		}
+
+		done, stop := a.watch(secret)
+
+		select {
+		case <-ctx.Done():
+			stop()
+			return
+		case <-a.loggedIn:
+			stop()
+			continue
+		case err := <-done:
+			stop()
+			if err != nil {
+				a.logger.LogCtx(ctx, "level", "warning", "message", "failed to renew Vault token", "stack", microerror.JSON(err))
+			}
+		}
+
+		for {
+			a.logger.LogCtx(ctx, "level", "debug", "message", "logging in to Vault")
+
+			err := a.Login()
+			if err == nil {
+				a.logger.LogCtx(ctx, "level", "debug", "message", "logged in to Vault")
+				break
+			}
+
+			a.logger.LogCtx(ctx, "level", "error", "message", "failed to log in to Vault", "stack", microerror.JSON(err))
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(a.retryInterval):
+			}
+		}
+	}
+}
+
+// watch keeps the given secret alive. The returned channel receives once the
+// secret has to be replaced by logging in again. The returned function stops
+// watching the secret.
+func (a *Authenticator) watch(secret *vaultclient.Secret) (<-chan error, func()) {
+	// Tokens without TTL never expire.
+	if secret == nil || secret.Auth == nil || secret.Auth.LeaseDuration == 0 {
+		return nil, func() {}
+	}
+
+	if secret.Auth.Renewable {
+		renewer, err := a.vaultClient.NewRenewer(&vaultclient.RenewerInput{Secret: secret})
+		if err != nil {
+			done := make(chan error, 1)
+			done <- microerror.Mask(err)
+			return done, func() {}
+		}
+
+		go renewer.Renew()
+
+		return renewer.DoneCh(), renewer.Stop
+	}
+
+	// Tokens which cannot be renewed are replaced after two thirds of their
+	// TTL.
+	done := make(chan error, 1)
+	lease := time.Duration(secret.Auth.LeaseDuration) * time.Second
+	t := time.AfterFunc(lease*2/3, func() {
+		done <- nil
+	})
+
+	return done, func() { t.Stop() }
+}
+
+// login writes the given login data to the given auth path and returns the
+// resulting auth secret. The token of the given client is not used for the
+// login request, since it might already have expired.
+func login(client *vaultclient.Client, path string, data map[string]interface{}) (*vaultclient.Secret, error) {
+	c, err := client.Clone()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	c.SetHeaders(client.Headers())
+	c.ClearToken()
+
+	secret, err := c.Logical().Write(path, data)
+	if err != nil {
+		return nil, microerror.Maskf(loginFailedError, "%s", err.Error())
+	}
+	if secret == nil || secret.Auth == nil {
+		return nil, microerror.Maskf(loginFailedError, "login response of '%s' does not contain auth data", path)
+	}
+
+	return secret, nil
+}
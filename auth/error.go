@@ -0,0 +1,21 @@
+package auth
+
+import "github.com/giantswarm/microerror"
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var loginFailedError = &microerror.Error{
+	Kind: "loginFailedError",
+}
+
+// IsLoginFailed asserts loginFailedError.
+func IsLoginFailed(err error) bool {
+	return microerror.Cause(err) == loginFailedError
+}
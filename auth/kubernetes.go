@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	vaultclient "github.com/hashicorp/vault/api"
+)
+
+type KubernetesConfig struct {
+	// JWTPath is the path of the service account token file. Defaults to
+	// /var/run/secrets/kubernetes.io/serviceaccount/token.
+	JWTPath string
+	// MountPath is the path the Kubernetes auth method is mounted at. Defaults
+	// to kubernetes.
+	MountPath string
+	Role      string
+}
+
+// Kubernetes authenticates using the Kubernetes auth method and the service
+// account token of the current pod. The token file is read on every login,
+// so that rotated service account tokens are picked up.
+type Kubernetes struct {
+	jwtPath   string
+	mountPath string
+	role      string
+}
+
+func NewKubernetes(config KubernetesConfig) (*Kubernetes, error) {
+	if config.JWTPath == "" {
+		config.JWTPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	}
+	if config.MountPath == "" {
+		config.MountPath = "kubernetes"
+	}
+	if config.Role == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Role must not be empty")
+	}
+
+	k := &Kubernetes{
+		jwtPath:   config.JWTPath,
+		mountPath: config.MountPath,
+		role:      config.Role,
+	}
+
+	return k, nil
+}
+
+func (k *Kubernetes) Login(client *vaultclient.Client) (*vaultclient.Secret, error) {
+	b, err := ioutil.ReadFile(k.jwtPath)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	v := map[string]interface{}{
+		"jwt":  strings.TrimSpace(string(b)),
+		"role": k.role,
+	}
+
+	secret, err := login(client, fmt.Sprintf("auth/%s/login", k.mountPath), v)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return secret, nil
+}
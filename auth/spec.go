@@ -0,0 +1,14 @@
+package auth
+
+import (
+	vaultclient "github.com/hashicorp/vault/api"
+)
+
+// Method authenticates against Vault using a specific auth method, e.g. a
+// static token, AppRole or Kubernetes service accounts.
+type Method interface {
+	// Login authenticates using the given Vault client and returns the secret
+	// holding the resulting token in its Auth field. Login must not set the
+	// token on the given client.
+	Login(client *vaultclient.Client) (*vaultclient.Secret, error)
+}
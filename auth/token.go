@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"github.com/giantswarm/microerror"
+	vaultclient "github.com/hashicorp/vault/api"
+)
+
+type TokenConfig struct {
+	Token string
+}
+
+// Token authenticates using a static token. Renewable tokens are kept alive by
+// the Authenticator, but cannot be replaced once they reached their maximum
+// TTL.
+type Token struct {
+	token string
+}
+
+func NewToken(config TokenConfig) (*Token, error) {
+	if config.Token == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Token must not be empty")
+	}
+
+	t := &Token{
+		token: config.Token,
+	}
+
+	return t, nil
+}
+
+func (t *Token) Login(client *vaultclient.Client) (*vaultclient.Secret, error) {
+	c, err := client.Clone()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	c.SetHeaders(client.Headers())
+	c.SetToken(t.token)
+
+	// Looking up the token verifies it and provides the information required
+	// to renew it.
+	secret, err := c.Auth().Token().LookupSelf()
+	if err != nil {
+		return nil, microerror.Maskf(loginFailedError, "%s", err.Error())
+	}
+
+	renewable, err := secret.TokenIsRenewable()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	ttl, err := secret.TokenTTL()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	s := &vaultclient.Secret{
+		Auth: &vaultclient.SecretAuth{
+			ClientToken:   t.token,
+			LeaseDuration: int(ttl.Seconds()),
+			Renewable:     renewable,
+		},
+	}
+
+	return s, nil
+}
@@ -0,0 +1,167 @@
+package vaultrole
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/hashicorp/vault/sdk/helper/parseutil"
+)
+
+// CanIssue checks whether Vault would issue the certificate described by the
+// given request using the given role, so that callers can fail before
+// contacting Vault. Names are matched against the allowed domains of the
+// role, which are its common name and alternative names, the way Vault does.
+//
+//   - A name equal to an allowed domain requires AllowBareDomains.
+//   - A subdomain of an allowed domain, including wildcards like
+//     *.example.com, requires AllowSubdomains.
+//   - Allowed domains containing * are matched as globs in case the role
+//     sets allow_glob_domains, see Role.Extra.
+//
+// localhost is allowed unless the role sets allow_localhost to false and any
+// name is allowed in case the role sets allow_any_name. IP SANs are allowed
+// unless the role sets allow_ip_sans to false. The TTL must not exceed the TTL
+// of the role. In case the request violates the role, a notIssuableError
+// listing every reason is returned.
+func CanIssue(role Role, request IssueConfig) error {
+	var reasons []string
+
+	if request.ID != role.ID {
+		reasons = append(reasons, fmt.Sprintf("cluster ID %q does not match the cluster ID %q of the role", request.ID, role.ID))
+	}
+	if !sameOrganizations(request.Organizations, role.Organizations) {
+		reasons = append(reasons, fmt.Sprintf("organizations %q do not match the organizations %q of the role", request.Organizations, role.Organizations))
+	}
+
+	if request.CommonName == "" {
+		reasons = append(reasons, "common name must not be empty")
+	} else if reason := checkName(role, request.CommonName); reason != "" {
+		reasons = append(reasons, fmt.Sprintf("common name %q %s", request.CommonName, reason))
+	}
+	for _, n := range request.AltNames {
+		if reason := checkName(role, n); reason != "" {
+			reasons = append(reasons, fmt.Sprintf("alternative name %q %s", n, reason))
+		}
+	}
+
+	for _, ip := range request.IPSANs {
+		if net.ParseIP(ip) == nil {
+			reasons = append(reasons, fmt.Sprintf("IP SAN %q is not a valid IP address", ip))
+		} else if !extraBool(role, "allow_ip_sans", true) {
+			reasons = append(reasons, fmt.Sprintf("IP SAN %q is not allowed since the role does not allow IP SANs", ip))
+		}
+	}
+
+	if request.TTL != "" {
+		ttl, err := parseutil.ParseDurationSecond(request.TTL)
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("TTL %q is not a valid duration", request.TTL))
+		} else if role.TTL > 0 && ttl > role.TTL {
+			reasons = append(reasons, fmt.Sprintf("TTL %s exceeds the TTL %s of the role", ttl, role.TTL))
+		}
+	}
+
+	if len(reasons) > 0 {
+		return microerror.Maskf(notIssuableError, "%s", strings.Join(reasons, "; "))
+	}
+
+	return nil
+}
+
+// checkName returns the reason why the given name is not allowed by the given
+// role, or an empty string in case it is allowed.
+func checkName(role Role, name string) string {
+	if extraBool(role, "allow_any_name", false) {
+		return ""
+	}
+	if name == "localhost" && extraBool(role, "allow_localhost", true) {
+		return ""
+	}
+
+	var allowed []string
+	if role.CommonName != "" {
+		allowed = append(allowed, role.CommonName)
+	}
+	allowed = append(allowed, role.AltNames...)
+
+	var bare, sub bool
+	for _, d := range allowed {
+		if name == d {
+			if role.AllowBareDomains {
+				return ""
+			}
+			bare = true
+		}
+		if strings.HasSuffix(name, "."+d) && len(name) > len(d)+1 {
+			if role.AllowSubdomains {
+				return ""
+			}
+			sub = true
+		}
+		if strings.Contains(d, "*") && extraBool(role, "allow_glob_domains", false) && matchGlob(d, name) {
+			return ""
+		}
+	}
+
+	switch {
+	case bare:
+		return "matches an allowed domain but the role does not allow bare domains"
+	case sub:
+		return "is a subdomain of an allowed domain but the role does not allow subdomains"
+	default:
+		return fmt.Sprintf("is not covered by the allowed domains %q", allowed)
+	}
+}
+
+// extraBool returns the boolean field of the given name of Role.Extra, or the
+// given default in case the field is not set or is not a boolean.
+func extraBool(role Role, name string, def bool) bool {
+	v, ok := role.Extra[name]
+	if !ok {
+		return def
+	}
+
+	b, err := decodeBool(roleField{Name: name, Type: fieldTypeBool}, v)
+	if err != nil {
+		return def
+	}
+
+	return b
+}
+
+// matchGlob matches the given name against the given pattern, where * matches
+// any sequence of characters, including dots, like Vault does.
+func matchGlob(pattern string, name string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == name
+	}
+
+	if !strings.HasPrefix(name, parts[0]) {
+		return false
+	}
+	name = name[len(parts[0]):]
+
+	for _, p := range parts[1 : len(parts)-1] {
+		i := strings.Index(name, p)
+		if i < 0 {
+			return false
+		}
+		name = name[i+len(p):]
+	}
+
+	return strings.HasSuffix(name, parts[len(parts)-1])
+}
+
+func sameOrganizations(a []string, b []string) bool {
+	a = append([]string{}, a...)
+	b = append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+
+	return reflect.DeepEqual(a, b)
+}
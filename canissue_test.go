@@ -0,0 +1,156 @@
+package vaultrole_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/vaultrole"
+)
+
+func Test_CanIssue(t *testing.T) {
+	role := vaultrole.Role{
+		AllowBareDomains: true,
+		AllowSubdomains:  true,
+		AltNames:         []string{"kubernetes", "kubernetes.default.svc"},
+		CommonName:       "al9qy.g8s.gigantic.io",
+		ID:               "al9qy",
+		Organizations:    []string{"api", "system:masters"},
+		TTL:              24 * time.Hour,
+	}
+
+	testCases := []struct {
+		name         string
+		role         func(vaultrole.Role) vaultrole.Role
+		request      vaultrole.IssueConfig
+		errorMatcher func(error) bool
+		reasons      []string
+	}{
+		{
+			name: "case 0: bare common name, subdomain, alternative name, IP SAN and TTL within the role",
+			request: vaultrole.IssueConfig{
+				AltNames:      []string{"api.al9qy.g8s.gigantic.io", "kubernetes", "localhost"},
+				CommonName:    "al9qy.g8s.gigantic.io",
+				ID:            "al9qy",
+				IPSANs:        []string{"10.0.0.1"},
+				Organizations: []string{"system:masters", "api"},
+				TTL:           "24h",
+			},
+		},
+		{
+			name: "case 1: bare domains are not allowed",
+			role: func(r vaultrole.Role) vaultrole.Role {
+				r.AllowBareDomains = false
+				return r
+			},
+			request:      vaultrole.IssueConfig{CommonName: "al9qy.g8s.gigantic.io", ID: "al9qy", Organizations: []string{"api", "system:masters"}},
+			errorMatcher: vaultrole.IsNotIssuable,
+			reasons:      []string{`common name "al9qy.g8s.gigantic.io" matches an allowed domain but the role does not allow bare domains`},
+		},
+		{
+			name: "case 2: subdomains are not allowed",
+			role: func(r vaultrole.Role) vaultrole.Role {
+				r.AllowSubdomains = false
+				return r
+			},
+			request:      vaultrole.IssueConfig{CommonName: "*.al9qy.g8s.gigantic.io", ID: "al9qy", Organizations: []string{"api", "system:masters"}},
+			errorMatcher: vaultrole.IsNotIssuable,
+			reasons:      []string{`common name "*.al9qy.g8s.gigantic.io" is a subdomain of an allowed domain but the role does not allow subdomains`},
+		},
+		{
+			name: "case 3: every violation is reported",
+			request: vaultrole.IssueConfig{
+				AltNames:      []string{"example.com"},
+				CommonName:    "xal9qy.g8s.gigantic.io",
+				ID:            "5xchu",
+				IPSANs:        []string{"10.0.0"},
+				Organizations: []string{"api"},
+				TTL:           "48h",
+			},
+			errorMatcher: vaultrole.IsNotIssuable,
+			reasons: []string{
+				`cluster ID "5xchu" does not match the cluster ID "al9qy" of the role`,
+				`organizations ["api"] do not match the organizations ["api" "system:masters"] of the role`,
+				`common name "xal9qy.g8s.gigantic.io" is not covered by the allowed domains ["al9qy.g8s.gigantic.io" "kubernetes" "kubernetes.default.svc"]`,
+				`alternative name "example.com" is not covered by the allowed domains ["al9qy.g8s.gigantic.io" "kubernetes" "kubernetes.default.svc"]`,
+				`IP SAN "10.0.0" is not a valid IP address`,
+				`TTL 48h0m0s exceeds the TTL 24h0m0s of the role`,
+			},
+		},
+		{
+			name: "case 4: globs are matched in case the role allows them",
+			role: func(r vaultrole.Role) vaultrole.Role {
+				r.AltNames = []string{"etcd*.al9qy.internal"}
+				r.Extra = map[string]interface{}{"allow_glob_domains": true}
+				return r
+			},
+			request: vaultrole.IssueConfig{CommonName: "etcd1.eu.al9qy.internal", ID: "al9qy", Organizations: []string{"api", "system:masters"}},
+		},
+		{
+			name: "case 5: globs are not matched in case the role does not allow them",
+			role: func(r vaultrole.Role) vaultrole.Role {
+				r.AltNames = []string{"etcd*.al9qy.internal"}
+				return r
+			},
+			request:      vaultrole.IssueConfig{CommonName: "etcd1.al9qy.internal", ID: "al9qy", Organizations: []string{"api", "system:masters"}},
+			errorMatcher: vaultrole.IsNotIssuable,
+			reasons:      []string{`common name "etcd1.al9qy.internal" is not covered by the allowed domains ["al9qy.g8s.gigantic.io" "etcd*.al9qy.internal"]`},
+		},
+		{
+			name: "case 6: IP SANs and localhost are not allowed",
+			role: func(r vaultrole.Role) vaultrole.Role {
+				r.Extra = map[string]interface{}{"allow_ip_sans": false, "allow_localhost": false}
+				return r
+			},
+			request:      vaultrole.IssueConfig{CommonName: "localhost", ID: "al9qy", IPSANs: []string{"127.0.0.1"}, Organizations: []string{"api", "system:masters"}},
+			errorMatcher: vaultrole.IsNotIssuable,
+			reasons: []string{
+				`common name "localhost" is not covered by the allowed domains ["al9qy.g8s.gigantic.io" "kubernetes" "kubernetes.default.svc"]`,
+				`IP SAN "127.0.0.1" is not allowed since the role does not allow IP SANs`,
+			},
+		},
+		{
+			name: "case 7: any name is allowed",
+			role: func(r vaultrole.Role) vaultrole.Role {
+				r.Extra = map[string]interface{}{"allow_any_name": true}
+				return r
+			},
+			request: vaultrole.IssueConfig{CommonName: "example.com", ID: "al9qy", Organizations: []string{"api", "system:masters"}},
+		},
+		{
+			name:         "case 8: empty common name",
+			request:      vaultrole.IssueConfig{ID: "al9qy", Organizations: []string{"api", "system:masters"}},
+			errorMatcher: vaultrole.IsNotIssuable,
+			reasons:      []string{"common name must not be empty"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := role
+			if tc.role != nil {
+				r = tc.role(r)
+			}
+
+			err := vaultrole.CanIssue(r, tc.request)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if err != nil {
+				expected := strings.Join(tc.reasons, "; ")
+				if !strings.HasSuffix(err.Error(), ": "+expected) {
+					t.Fatalf("error == %q, want reasons %q", err.Error(), expected)
+				}
+			}
+		})
+	}
+}
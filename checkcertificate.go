@@ -0,0 +1,78 @@
+package vaultrole
+
+import (
+	"crypto/x509"
+	"fmt"
+	"time"
+)
+
+const (
+	ViolationKindIPSAN        = "ipSAN"
+	ViolationKindLifetime     = "lifetime"
+	ViolationKindName         = "name"
+	ViolationKindOrganization = "organization"
+)
+
+// Violation is a property of an issued certificate which the role it was
+// issued by does not allow anymore, as reported by CheckCertificate.
+type Violation struct {
+	// Kind is one of the ViolationKind constants.
+	Kind string
+	// Value is the offending value, e.g. the name or organization.
+	Value  string
+	Reason string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s %q %s", v.Kind, v.Value, v.Reason)
+}
+
+// CheckCertificate reports the properties of the given certificate which the
+// given role would not issue anymore, e.g. after the role definition changed.
+// Names and IP SANs are checked like CanIssue does. Organizations of the
+// certificate must be organizations of the role and the remaining lifetime of
+// the certificate must not exceed the TTL of the role. An empty result means
+// the certificate is compliant with the role.
+func CheckCertificate(role Role, cert *x509.Certificate) []Violation {
+	var violations []Violation
+
+	names := []string{}
+	if cert.Subject.CommonName != "" {
+		names = append(names, cert.Subject.CommonName)
+	}
+	for _, n := range cert.DNSNames {
+		if n != cert.Subject.CommonName {
+			names = append(names, n)
+		}
+	}
+	for _, n := range names {
+		if reason := checkName(role, n); reason != "" {
+			violations = append(violations, Violation{Kind: ViolationKindName, Value: n, Reason: reason})
+		}
+	}
+
+	if len(cert.IPAddresses) > 0 && !extraBool(role, "allow_ip_sans", true) {
+		for _, ip := range cert.IPAddresses {
+			violations = append(violations, Violation{Kind: ViolationKindIPSAN, Value: ip.String(), Reason: "is not allowed since the role does not allow IP SANs"})
+		}
+	}
+
+	allowed := map[string]bool{}
+	for _, o := range role.Organizations {
+		allowed[o] = true
+	}
+	for _, o := range cert.Subject.Organization {
+		if !allowed[o] {
+			violations = append(violations, Violation{Kind: ViolationKindOrganization, Value: o, Reason: fmt.Sprintf("is not an organization of the role, which are %q", role.Organizations)})
+		}
+	}
+
+	if role.TTL > 0 {
+		remaining := time.Until(cert.NotAfter).Round(time.Second)
+		if remaining > role.TTL {
+			violations = append(violations, Violation{Kind: ViolationKindLifetime, Value: remaining.String(), Reason: fmt.Sprintf("exceeds the TTL %s of the role", role.TTL)})
+		}
+	}
+
+	return violations
+}
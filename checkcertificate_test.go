@@ -0,0 +1,95 @@
+package vaultrole_test
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/vaultrole"
+)
+
+func Test_CheckCertificate(t *testing.T) {
+	role := vaultrole.Role{
+		AllowBareDomains: true,
+		AllowSubdomains:  true,
+		AltNames:         []string{"kubernetes"},
+		CommonName:       "al9qy.g8s.gigantic.io",
+		ID:               "al9qy",
+		Organizations:    []string{"api", "system:masters"},
+		TTL:              24 * time.Hour,
+	}
+
+	testCases := []struct {
+		name     string
+		role     func(vaultrole.Role) vaultrole.Role
+		cert     *x509.Certificate
+		expected []string
+	}{
+		{
+			name: "case 0: compliant certificate",
+			cert: &x509.Certificate{
+				DNSNames:    []string{"api.al9qy.g8s.gigantic.io", "kubernetes"},
+				IPAddresses: []net.IP{net.ParseIP("10.0.0.1")},
+				NotAfter:    time.Now().Add(12 * time.Hour),
+				Subject:     pkix.Name{CommonName: "api.al9qy.g8s.gigantic.io", Organization: []string{"api"}},
+			},
+		},
+		{
+			name: "case 1: role no longer allows subdomains, an alternative name and the organization",
+			role: func(r vaultrole.Role) vaultrole.Role {
+				r.AllowSubdomains = false
+				r.AltNames = nil
+				r.Organizations = []string{"system:masters"}
+				return r
+			},
+			cert: &x509.Certificate{
+				DNSNames: []string{"api.al9qy.g8s.gigantic.io", "kubernetes"},
+				NotAfter: time.Now().Add(12 * time.Hour),
+				Subject:  pkix.Name{CommonName: "api.al9qy.g8s.gigantic.io", Organization: []string{"api"}},
+			},
+			expected: []string{
+				`name "api.al9qy.g8s.gigantic.io" is a subdomain of an allowed domain but the role does not allow subdomains`,
+				`name "kubernetes" is not covered by the allowed domains ["al9qy.g8s.gigantic.io"]`,
+				`organization "api" is not an organization of the role, which are ["system:masters"]`,
+			},
+		},
+		{
+			name: "case 2: remaining lifetime exceeds the reduced TTL and IP SANs are not allowed",
+			role: func(r vaultrole.Role) vaultrole.Role {
+				r.TTL = time.Hour
+				r.Extra = map[string]interface{}{"allow_ip_sans": false}
+				return r
+			},
+			cert: &x509.Certificate{
+				IPAddresses: []net.IP{net.ParseIP("10.0.0.1")},
+				NotAfter:    time.Now().Add(12 * time.Hour),
+				Subject:     pkix.Name{CommonName: "al9qy.g8s.gigantic.io"},
+			},
+			expected: []string{
+				`ipSAN "10.0.0.1" is not allowed since the role does not allow IP SANs`,
+				`lifetime "12h0m0s" exceeds the TTL 1h0m0s of the role`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := role
+			if tc.role != nil {
+				r = tc.role(r)
+			}
+
+			var violations []string
+			for _, v := range vaultrole.CheckCertificate(r, tc.cert) {
+				violations = append(violations, v.String())
+			}
+
+			if !reflect.DeepEqual(violations, tc.expected) {
+				t.Fatalf("violations == %#v, want %#v", violations, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/hashicorp/vault/sdk/helper/parseutil"
+
+	"github.com/giantswarm/vaultrole"
+	"github.com/giantswarm/vaultrole/key"
+	"github.com/giantswarm/vaultrole/manifest"
+)
+
+const (
+	actionCreate    = "create"
+	actionPrune     = "prune"
+	actionUnchanged = "unchanged"
+	actionUpdate    = "update"
+)
+
+// diffEntry describes the difference between a role defined by a manifest and
+// the role in Vault.
+type diffEntry struct {
+	Action        string   `json:"action" yaml:"action"`
+	ClusterID     string   `json:"clusterID" yaml:"clusterID"`
+	Namespace     string   `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Name          string   `json:"name" yaml:"name"`
+	Organizations []string `json:"organizations" yaml:"organizations"`
+	// Changes lists the fields which differ for updated roles.
+	Changes []string `json:"changes,omitempty" yaml:"changes,omitempty"`
+}
+
+// cluster identifies the PKI backend of a cluster within a namespace.
+type cluster struct {
+	id        string
+	namespace string
+}
+
+func runDiff(args []string, stdout io.Writer) error {
+	var f flags
+	fs := newFlagSet("diff", &f)
+	fs.StringVar(&f.manifest, "manifest", "", "Path of the manifest defining the roles.")
+	registerOutputFlag(fs, &f)
+	registerPruneBaseRoleFlag(fs, &f)
+	err := fs.Parse(args)
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "%s", err.Error())
+	}
+	err = validateOutput(f.output)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r, err := newVaultRole("", false)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	entries, err := diffManifest(r, f.manifest, f.pruneBaseRole)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = printDiff(stdout, f.output, entries)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// diffManifest loads the manifest at the given path and compares the roles it
+// defines with the roles of the affected clusters in Vault. See computeDiff
+// for pruneBaseRole.
+func diffManifest(r *vaultrole.VaultRole, path string, pruneBaseRole bool) ([]diffEntry, error) {
+	if path == "" {
+		return nil, microerror.Maskf(invalidFlagsError, "-manifest must not be empty")
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	configs, err := manifest.Load(b)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	// The command does not configure any profile, so roles referencing one
+	// are rejected before contacting Vault.
+	for _, c := range configs {
+		if c.Profile != "" {
+			return nil, microerror.Maskf(invalidFlagsError, "role of cluster %q references profile %q, but profiles are not supported by the vaultrole command, define the fields of the role in the manifest instead", c.ID, c.Profile)
+		}
+	}
+
+	var desired []vaultrole.CreateConfig
+	for _, c := range configs {
+		merged, err := r.MergeProfile(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		desired = append(desired, merged)
+	}
+
+	live := map[cluster][]vaultrole.NamedRole{}
+	for _, c := range desired {
+		k := cluster{id: c.ID, namespace: c.Namespace}
+		if _, ok := live[k]; ok {
+			continue
+		}
+
+		roles, err := r.List(vaultrole.ListConfig{ID: c.ID, Namespace: c.Namespace})
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		live[k] = roles
+	}
+
+	entries, err := computeDiff(desired, live, pruneBaseRole)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return entries, nil
+}
+
+// computeDiff compares the desired roles with the live roles of their
+// clusters. Live roles of those clusters which are not desired are reported to
+// be pruned. The base role of a cluster without organizations, which is
+// created when setting up its PKI, is only reported to be pruned in case
+// pruneBaseRole is true.
+func computeDiff(desired []vaultrole.CreateConfig, live map[cluster][]vaultrole.NamedRole, pruneBaseRole bool) ([]diffEntry, error) {
+	var entries []diffEntry
+
+	var clusters []cluster
+	wanted := map[cluster]map[string]bool{}
+	for _, c := range desired {
+		k := cluster{id: c.ID, namespace: c.Namespace}
+		if wanted[k] == nil {
+			wanted[k] = map[string]bool{}
+			clusters = append(clusters, k)
+		}
+
+		organizations := append([]string(nil), c.Organizations...)
+		name := key.RoleName(c.ID, organizations)
+		wanted[k][name] = true
+
+		e := diffEntry{
+			ClusterID:     c.ID,
+			Namespace:     c.Namespace,
+			Name:          name,
+			Organizations: organizations,
+		}
+
+		current, ok := findRole(live[k], name)
+		if !ok {
+			e.Action = actionCreate
+			entries = append(entries, e)
+			continue
+		}
+
+		changes, err := compareRole(c, current)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if len(changes) == 0 {
+			e.Action = actionUnchanged
+		} else {
+			e.Action = actionUpdate
+			e.Changes = changes
+		}
+		entries = append(entries, e)
+	}
+
+	for _, k := range clusters {
+		var prune []diffEntry
+		for _, n := range live[k] {
+			if wanted[k][n.Name] {
+				continue
+			}
+			if n.Name == key.RoleName(k.id, nil) && !pruneBaseRole {
+				continue
+			}
+
+			prune = append(prune, diffEntry{
+				Action:        actionPrune,
+				ClusterID:     k.id,
+				Namespace:     k.namespace,
+				Name:          n.Name,
+				Organizations: n.Role.Organizations,
+			})
+		}
+
+		sort.Slice(prune, func(i, j int) bool { return prune[i].Name < prune[j].Name })
+		entries = append(entries, prune...)
+	}
+
+	return entries, nil
+}
+
+// compareRole returns the names of the fields in which the desired config
+// differs from the current role.
+func compareRole(desired vaultrole.CreateConfig, current vaultrole.Role) ([]string, error) {
+	var changes []string
+
+	if desired.AllowBareDomains != current.AllowBareDomains {
+		changes = append(changes, "allowBareDomains")
+	}
+	if desired.AllowSubdomains != current.AllowSubdomains {
+		changes = append(changes, "allowSubdomains")
+	}
+	if !equalSets(desired.AltNames, current.AltNames) {
+		changes = append(changes, "altNames")
+	}
+
+	var ttl int64
+	if desired.TTL != "" {
+		d, err := parseutil.ParseDurationSecond(desired.TTL)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		ttl = int64(d)
+	}
+	if ttl != int64(current.TTL) {
+		changes = append(changes, "ttl")
+	}
+
+	return changes, nil
+}
+
+func equalSets(a, b []string) bool {
+	x := append([]string{}, a...)
+	y := append([]string{}, b...)
+	sort.Strings(x)
+	sort.Strings(y)
+
+	return reflect.DeepEqual(x, y)
+}
+
+func findRole(roles []vaultrole.NamedRole, name string) (vaultrole.Role, bool) {
+	for _, n := range roles {
+		if n.Name == name {
+			return n.Role, true
+		}
+	}
+
+	return vaultrole.Role{}, false
+}
+
+func printDiff(w io.Writer, output string, entries []diffEntry) error {
+	header := []string{"ACTION", "CLUSTER", "NAMESPACE", "NAME", "ORGANIZATIONS", "CHANGES"}
+
+	var rows [][]string
+	for _, e := range entries {
+		rows = append(rows, []string{
+			e.Action,
+			e.ClusterID,
+			orNone(e.Namespace),
+			shortName(e.Name),
+			orNone(strings.Join(e.Organizations, ",")),
+			orNone(strings.Join(e.Changes, ",")),
+		})
+	}
+
+	if entries == nil {
+		entries = []diffEntry{}
+	}
+
+	err := printOutput(w, output, entries, header, rows)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/vaultrole"
+	"github.com/giantswarm/vaultrole/key"
+	"github.com/giantswarm/vaultrole/vaultroletest"
+)
+
+func Test_computeDiff(t *testing.T) {
+	live := map[cluster][]vaultrole.NamedRole{
+		{id: "al9qy"}: {
+			{Name: "role-al9qy", Role: vaultrole.Role{ID: "al9qy", TTL: time.Hour}},
+			{Name: "role-org-bbb", Role: vaultrole.Role{ID: "al9qy", Organizations: []string{"also-old"}}},
+			{Name: key.RoleName("al9qy", []string{"api"}), Role: vaultrole.Role{ID: "al9qy", Organizations: []string{"api"}, TTL: 2 * time.Hour}},
+			{Name: "role-org-aaa", Role: vaultrole.Role{ID: "al9qy", Organizations: []string{"old"}}},
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		desired       []vaultrole.CreateConfig
+		pruneBaseRole bool
+		expected      []string
+	}{
+		{
+			name: "case 0: roles are created, updated and pruned",
+			desired: []vaultrole.CreateConfig{
+				{ID: "al9qy", TTL: "1h"},
+				{ID: "al9qy", Organizations: []string{"api"}, AltNames: []string{"kubernetes"}, TTL: "1h"},
+				{ID: "al9qy", Organizations: []string{"etcd"}, TTL: "1h"},
+			},
+			expected: []string{
+				"unchanged  ",
+				"update api altNames,ttl",
+				"create etcd ",
+				"prune old ",
+				"prune also-old ",
+			},
+		},
+		{
+			name: "case 1: base role is not pruned by default",
+			desired: []vaultrole.CreateConfig{
+				{ID: "al9qy", Organizations: []string{"api"}, TTL: "2h"},
+			},
+			expected: []string{
+				"unchanged api ",
+				"prune old ",
+				"prune also-old ",
+			},
+		},
+		{
+			name: "case 2: base role is pruned when opted in",
+			desired: []vaultrole.CreateConfig{
+				{ID: "al9qy", Organizations: []string{"api"}, TTL: "2h"},
+			},
+			pruneBaseRole: true,
+			expected: []string{
+				"unchanged api ",
+				"prune  ",
+				"prune old ",
+				"prune also-old ",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			entries, err := computeDiff(tc.desired, live, tc.pruneBaseRole)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var actions []string
+			for _, e := range entries {
+				actions = append(actions, e.Action+" "+strings.Join(e.Organizations, ",")+" "+strings.Join(e.Changes, ","))
+			}
+
+			if !reflect.DeepEqual(actions, tc.expected) {
+				t.Fatalf("actions == %#v, want %#v", actions, tc.expected)
+			}
+		})
+	}
+}
+
+func Test_run_Prune(t *testing.T) {
+	s := vaultroletest.NewServer()
+	defer s.Close()
+	s.Mount("", "pki-al9qy")
+
+	os.Setenv("VAULT_ADDR", s.URL())
+	os.Setenv("VAULT_TOKEN", "test")
+	defer os.Unsetenv("VAULT_ADDR")
+	defer os.Unsetenv("VAULT_TOKEN")
+
+	for _, orgs := range []string{"", "api", "old"} {
+		args := []string{"create", "-cluster-id", "al9qy", "-ttl", "1h", "-common-name-format", "%s.g8s.example.com"}
+		if orgs != "" {
+			args = append(args, "-organizations", orgs)
+		}
+		err := run(args, &bytes.Buffer{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := ioutil.TempFile("", "manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("roles:\n- clusterID: al9qy\n  organizations:\n  - api\n  ttl: 1h\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	err = run([]string{"prune", "-manifest", f.Name()}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := s.Data("", key.WriteRolePath("al9qy", nil)); !ok {
+		t.Fatal("expected base role not to be pruned")
+	}
+	if _, ok := s.Data("", key.WriteRolePath("al9qy", []string{"old"})); ok {
+		t.Fatal("expected role of organization old to be pruned")
+	}
+
+	err = run([]string{"prune", "-manifest", f.Name(), "-prune-base-role"}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := s.Data("", key.WriteRolePath("al9qy", nil)); ok {
+		t.Fatal("expected base role to be pruned when opted in")
+	}
+	if _, ok := s.Data("", key.WriteRolePath("al9qy", []string{"api"})); !ok {
+		t.Fatal("expected role defined by the manifest to be kept")
+	}
+}
+
+func Test_run_List(t *testing.T) {
+	s := vaultroletest.NewServer()
+	defer s.Close()
+	s.Mount("", "pki-al9qy")
+
+	os.Setenv("VAULT_ADDR", s.URL())
+	os.Setenv("VAULT_TOKEN", "test")
+	defer os.Unsetenv("VAULT_ADDR")
+	defer os.Unsetenv("VAULT_TOKEN")
+
+	err := run([]string{"create", "-cluster-id", "al9qy", "-organizations", "api,system:masters", "-alt-names", "kubernetes", "-ttl", "1h", "-common-name-format", "%s.g8s.example.com"}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	err = run([]string{"list", "-cluster-id", "al9qy", "-output", "yaml"}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `- name: role-org-7395c031992f478e2e0e8d3198272008d407e1bc209c0cd52048fdebdd4ac1e0afd1d904044d9a9a2b0fe515579a56a4daf2aea7092518218ef985371890109f
+  clusterID: al9qy
+  commonName: al9qy.g8s.example.com
+  organizations:
+    - api
+    - system:masters
+  altNames:
+    - kubernetes
+  ttl: 1h0m0s
+  allowBareDomains: false
+  allowSubdomains: false
+`
+	if out.String() != expected {
+		t.Fatalf("output == %q, want %q", out.String(), expected)
+	}
+
+	err = run([]string{"list"}, &out)
+	if !IsInvalidFlags(err) {
+		t.Fatalf("expected invalid flags error got %#v", err)
+	}
+}
+
+func Test_run_Diff_Profile(t *testing.T) {
+	f, err := ioutil.TempFile("", "manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("roles:\n- clusterID: al9qy\n  profile: api\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	for _, command := range []string{"diff", "prune"} {
+		err = run([]string{command, "-manifest", f.Name()}, &bytes.Buffer{})
+		if !IsInvalidFlags(err) {
+			t.Fatalf("%s: expected invalid flags error got %#v", command, err)
+		}
+		if !strings.Contains(err.Error(), `references profile "api"`) {
+			t.Fatalf("%s: unexpected error %q", command, err.Error())
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Command vaultrole inspects and manages the Vault PKI roles of clusters. The
+// Vault address and token are read from the VAULT_ADDR and VAULT_TOKEN
+// environment variables. Setting VAULTROLE_DEBUG prints errors along with
+// their stack.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	vaultclient "github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/vaultrole"
+)
+
+const usage = `Usage: vaultrole <command> [flags]
+
+Commands:
+  list    List the roles of a cluster.
+  get     Show the role of a cluster and set of organizations.
+  create  Create a role.
+  update  Update a role.
+  delete  Delete a role.
+  diff    Compare the roles defined by a manifest with Vault.
+  prune   Delete roles of the clusters of a manifest the manifest does not define.
+  migrate Rewrite roles stored in the legacy comma joined format of older Vault versions.
+
+Run vaultrole <command> -h for the flags of a command.
+`
+
+type command func(args []string, stdout io.Writer) error
+
+func main() {
+	err := run(os.Args[1:], os.Stdout)
+	if err != nil {
+		printError(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// printError prints the message of the given error, including the details
+// given when masking it, e.g. the usage text. In case VAULTROLE_DEBUG is set,
+// the stack of the error is printed as JSON instead.
+func printError(w io.Writer, err error) {
+	if os.Getenv("VAULTROLE_DEBUG") != "" {
+		fmt.Fprintf(w, "%s\n", microerror.JSON(err))
+		return
+	}
+
+	fmt.Fprintf(w, "%s\n", err.Error())
+}
+
+func run(args []string, stdout io.Writer) error {
+	commands := map[string]command{
+		"create":  runCreate,
+		"delete":  runDelete,
+		"diff":    runDiff,
+		"get":     runGet,
+		"list":    runList,
+		"migrate": runMigrate,
+		"prune":   runPrune,
+		"update":  runUpdate,
+	}
+
+	if len(args) == 0 {
+		return microerror.Maskf(invalidFlagsError, "%s", usage)
+	}
+
+	c, ok := commands[args[0]]
+	if !ok {
+		return microerror.Maskf(invalidFlagsError, "unknown command %q\n\n%s", args[0], usage)
+	}
+
+	err := c(args[1:], stdout)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// flags holds the flags shared by the commands. Each command only registers
+// the flags it uses.
+type flags struct {
+	allowBareDomains bool
+	allowSubdomains  bool
+	altNames         string
+	clusterID        string
+	commonNameFormat string
+	dryRun           bool
+	manifest         string
+	namespace        string
+	organizations    string
+	output           string
+	pruneBaseRole    bool
+	ttl              string
+}
+
+func newFlagSet(name string, f *flags) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&f.namespace, "namespace", "", "Vault Enterprise namespace of the roles.")
+	return fs
+}
+
+func registerClusterFlags(fs *flag.FlagSet, f *flags) {
+	fs.StringVar(&f.clusterID, "cluster-id", "", "ID of the cluster owning the PKI backend.")
+}
+
+func registerOrganizationsFlag(fs *flag.FlagSet, f *flags) {
+	fs.StringVar(&f.organizations, "organizations", "", "Comma separated list of organizations identifying the role.")
+}
+
+func registerOutputFlag(fs *flag.FlagSet, f *flags) {
+	fs.StringVar(&f.output, "output", outputTable, "Output format, one of table, json or yaml.")
+}
+
+func registerPruneBaseRoleFlag(fs *flag.FlagSet, f *flags) {
+	fs.BoolVar(&f.pruneBaseRole, "prune-base-role", false, "Also prune the role created when setting up the PKI of a cluster, which has no organizations.")
+}
+
+func registerRoleFlags(fs *flag.FlagSet, f *flags) {
+	fs.BoolVar(&f.allowBareDomains, "allow-bare-domains", false, "Allow issuing certificates for the allowed domains themselves.")
+	fs.BoolVar(&f.allowSubdomains, "allow-subdomains", false, "Allow issuing certificates for subdomains of the allowed domains.")
+	fs.StringVar(&f.altNames, "alt-names", "", "Comma separated list of alternative names.")
+	fs.StringVar(&f.commonNameFormat, "common-name-format", "", "Format of the common name, e.g. %s.g8s.example.com.")
+	fs.StringVar(&f.ttl, "ttl", "", "TTL of certificates issued by the role, e.g. 8640h.")
+}
+
+func validateClusterID(f flags) error {
+	if f.clusterID == "" {
+		return microerror.Maskf(invalidFlagsError, "-cluster-id must not be empty")
+	}
+
+	return nil
+}
+
+// newVaultRole creates a VaultRole using a Vault client configured by the
+// standard Vault environment variables. Commands which do not write roles do
+// not need the common name format, so a placeholder is used in case it is
+// empty.
+func newVaultRole(commonNameFormat string, dryRun bool) (*vaultrole.VaultRole, error) {
+	logger, err := micrologger.New(micrologger.Config{IOWriter: os.Stderr})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	client, err := vaultclient.NewClient(vaultclient.DefaultConfig())
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if commonNameFormat == "" {
+		commonNameFormat = "%s"
+	}
+
+	r, err := vaultrole.New(vaultrole.Config{
+		Logger:      logger,
+		VaultClient: client,
+
+		CommonNameFormat: commonNameFormat,
+		DryRun:           dryRun,
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return r, nil
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		list = append(list, strings.TrimSpace(v))
+	}
+
+	return list
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func Test_run_Errors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "case 0: no arguments",
+			args:     nil,
+			expected: "Usage: vaultrole <command> [flags]",
+		},
+		{
+			name:     "case 1: unknown command",
+			args:     []string{"foo"},
+			expected: `unknown command "foo"`,
+		},
+		{
+			name:     "case 2: missing cluster ID",
+			args:     []string{"get"},
+			expected: "-cluster-id must not be empty",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := run(tc.args, &bytes.Buffer{})
+			if !IsInvalidFlags(err) {
+				t.Fatalf("expected invalid flags error got %#v", err)
+			}
+
+			var stderr bytes.Buffer
+			printError(&stderr, err)
+			if !strings.Contains(stderr.String(), tc.expected) {
+				t.Fatalf("output == %q, want containing %q", stderr.String(), tc.expected)
+			}
+		})
+	}
+}
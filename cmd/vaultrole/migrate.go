@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/vaultrole"
+)
+
+type migrateOutput struct {
+	Name   string   `json:"name" yaml:"name"`
+	Fields []string `json:"fields" yaml:"fields"`
+}
+
+func runMigrate(args []string, stdout io.Writer) error {
+	var f flags
+	fs := newFlagSet("migrate", &f)
+	registerClusterFlags(fs, &f)
+	fs.BoolVar(&f.dryRun, "dry-run", false, "Only print which roles would be migrated.")
+	registerOutputFlag(fs, &f)
+	err := fs.Parse(args)
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "%s", err.Error())
+	}
+	err = validateClusterID(f)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	err = validateOutput(f.output)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r, err := newVaultRole("", f.dryRun)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	results, err := r.Migrate(vaultrole.MigrateConfig{ID: f.clusterID, Namespace: f.namespace})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	header := []string{"NAME", "FIELDS"}
+
+	migrated := []migrateOutput{}
+	var rows [][]string
+	for _, m := range results {
+		migrated = append(migrated, migrateOutput(m))
+		rows = append(rows, []string{shortName(m.Name), strings.Join(m.Fields, ",")})
+	}
+
+	err = printOutput(stdout, f.output, migrated, header, rows)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
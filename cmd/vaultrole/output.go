@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/giantswarm/microerror"
+	"gopkg.in/yaml.v3"
+
+	"github.com/giantswarm/vaultrole"
+)
+
+const (
+	outputJSON  = "json"
+	outputTable = "table"
+	outputYAML  = "yaml"
+)
+
+// roleOutput is the representation of a role printed by the commands.
+type roleOutput struct {
+	Name             string   `json:"name" yaml:"name"`
+	ClusterID        string   `json:"clusterID" yaml:"clusterID"`
+	CommonName       string   `json:"commonName,omitempty" yaml:"commonName,omitempty"`
+	Organizations    []string `json:"organizations" yaml:"organizations"`
+	AltNames         []string `json:"altNames" yaml:"altNames"`
+	TTL              string   `json:"ttl" yaml:"ttl"`
+	AllowBareDomains bool     `json:"allowBareDomains" yaml:"allowBareDomains"`
+	AllowSubdomains  bool     `json:"allowSubdomains" yaml:"allowSubdomains"`
+}
+
+func newRoleOutput(name string, role vaultrole.Role) roleOutput {
+	return roleOutput{
+		Name:             name,
+		ClusterID:        role.ID,
+		CommonName:       role.CommonName,
+		Organizations:    role.Organizations,
+		AltNames:         role.AltNames,
+		TTL:              role.TTL.String(),
+		AllowBareDomains: role.AllowBareDomains,
+		AllowSubdomains:  role.AllowSubdomains,
+	}
+}
+
+func validateOutput(output string) error {
+	switch output {
+	case outputJSON, outputTable, outputYAML:
+		return nil
+	default:
+		return microerror.Maskf(invalidFlagsError, "-output must be one of %s, %s or %s", outputTable, outputJSON, outputYAML)
+	}
+}
+
+// printOutput writes v in the given output format. The table format is rendered
+// from the given header and rows.
+func printOutput(w io.Writer, output string, v interface{}, header []string, rows [][]string) error {
+	switch output {
+	case outputJSON:
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		err := e.Encode(v)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+	case outputYAML:
+		e := yaml.NewEncoder(w)
+		e.SetIndent(2)
+		err := e.Encode(v)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		err = e.Close()
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+	default:
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+		for _, r := range rows {
+			fmt.Fprintln(tw, strings.Join(r, "\t"))
+		}
+		err := tw.Flush()
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+func printRoles(w io.Writer, output string, roles []roleOutput) error {
+	header := []string{"NAME", "ORGANIZATIONS", "ALT NAMES", "TTL", "BARE DOMAINS", "SUBDOMAINS"}
+
+	var rows [][]string
+	for _, r := range roles {
+		rows = append(rows, []string{
+			shortName(r.Name),
+			orNone(strings.Join(r.Organizations, ",")),
+			orNone(strings.Join(r.AltNames, ",")),
+			r.TTL,
+			fmt.Sprintf("%t", r.AllowBareDomains),
+			fmt.Sprintf("%t", r.AllowSubdomains),
+		})
+	}
+
+	if roles == nil {
+		roles = []roleOutput{}
+	}
+
+	err := printOutput(w, output, roles, header, rows)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+
+	return s
+}
+
+// shortName abbreviates the organization hash of role names for the table
+// output, which already shows the organizations themselves.
+func shortName(name string) string {
+	const prefix = "role-org-"
+	if strings.HasPrefix(name, prefix) && len(name) > len(prefix)+12 {
+		return name[:len(prefix)+12] + "…"
+	}
+
+	return name
+}
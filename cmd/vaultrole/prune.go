@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/vaultrole"
+	"github.com/giantswarm/vaultrole/key"
+)
+
+func runPrune(args []string, stdout io.Writer) error {
+	var f flags
+	fs := newFlagSet("prune", &f)
+	fs.StringVar(&f.manifest, "manifest", "", "Path of the manifest defining the roles to keep.")
+	fs.BoolVar(&f.dryRun, "dry-run", false, "Only print what would be deleted.")
+	registerOutputFlag(fs, &f)
+	registerPruneBaseRoleFlag(fs, &f)
+	err := fs.Parse(args)
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "%s", err.Error())
+	}
+	err = validateOutput(f.output)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r, err := newVaultRole("", f.dryRun)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	entries, err := diffManifest(r, f.manifest, f.pruneBaseRole)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var pruned []diffEntry
+	for _, e := range entries {
+		if e.Action != actionPrune {
+			continue
+		}
+
+		// Roles which have not been created by vaultrole cannot be addressed
+		// by their organizations and are left alone.
+		organizations := append([]string(nil), e.Organizations...)
+		if key.RoleName(e.ClusterID, organizations) != e.Name {
+			fmt.Fprintf(stdout, "skipping role %s of cluster %s not managed by vaultrole\n", e.Name, e.ClusterID)
+			continue
+		}
+
+		err := r.Delete(vaultrole.DeleteConfig{ID: e.ClusterID, Namespace: e.Namespace, Organizations: e.Organizations})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		pruned = append(pruned, e)
+	}
+
+	err = printDiff(stdout, f.output, pruned)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
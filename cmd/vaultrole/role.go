@@ -0,0 +1,200 @@
+package main
+
+import (
+	"io"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/vaultrole"
+	"github.com/giantswarm/vaultrole/key"
+)
+
+func runList(args []string, stdout io.Writer) error {
+	var f flags
+	fs := newFlagSet("list", &f)
+	registerClusterFlags(fs, &f)
+	registerOutputFlag(fs, &f)
+	err := fs.Parse(args)
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "%s", err.Error())
+	}
+	err = validateClusterID(f)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	err = validateOutput(f.output)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r, err := newVaultRole("", false)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	roles, err := r.List(vaultrole.ListConfig{ID: f.clusterID, Namespace: f.namespace})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var out []roleOutput
+	for _, n := range roles {
+		out = append(out, newRoleOutput(n.Name, n.Role))
+	}
+
+	err = printRoles(stdout, f.output, out)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func runGet(args []string, stdout io.Writer) error {
+	var f flags
+	fs := newFlagSet("get", &f)
+	registerClusterFlags(fs, &f)
+	registerOrganizationsFlag(fs, &f)
+	registerOutputFlag(fs, &f)
+	fs.StringVar(&f.commonNameFormat, "common-name-format", "", "Format the common name of the role is verified against, e.g. %s.g8s.example.com.")
+	err := fs.Parse(args)
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "%s", err.Error())
+	}
+	err = validateClusterID(f)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	err = validateOutput(f.output)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r, err := newVaultRole(f.commonNameFormat, false)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	organizations := splitList(f.organizations)
+
+	// The common name is only verified in case a format is given.
+	role, err := r.Search(vaultrole.SearchConfig{ID: f.clusterID, Namespace: f.namespace, Organizations: organizations})
+	if vaultrole.IsCommonNameMismatch(err) && f.commonNameFormat == "" {
+		// fall through
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = printRoles(stdout, f.output, []roleOutput{newRoleOutput(key.RoleName(f.clusterID, organizations), role)})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func runCreate(args []string, stdout io.Writer) error {
+	f, err := parseWriteFlags("create", args)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r, err := newVaultRole(f.commonNameFormat, f.dryRun)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	c := vaultrole.CreateConfig{
+		AllowBareDomains: f.allowBareDomains,
+		AllowSubdomains:  f.allowSubdomains,
+		AltNames:         splitList(f.altNames),
+		ID:               f.clusterID,
+		Namespace:        f.namespace,
+		Organizations:    splitList(f.organizations),
+		TTL:              f.ttl,
+	}
+	err = r.Create(c)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func runUpdate(args []string, stdout io.Writer) error {
+	f, err := parseWriteFlags("update", args)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r, err := newVaultRole(f.commonNameFormat, f.dryRun)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	c := vaultrole.UpdateConfig{
+		AllowBareDomains: f.allowBareDomains,
+		AllowSubdomains:  f.allowSubdomains,
+		AltNames:         splitList(f.altNames),
+		ID:               f.clusterID,
+		Namespace:        f.namespace,
+		Organizations:    splitList(f.organizations),
+		TTL:              f.ttl,
+	}
+	err = r.Update(c)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func runDelete(args []string, stdout io.Writer) error {
+	var f flags
+	fs := newFlagSet("delete", &f)
+	registerClusterFlags(fs, &f)
+	registerOrganizationsFlag(fs, &f)
+	fs.BoolVar(&f.dryRun, "dry-run", false, "Only log what would be deleted.")
+	err := fs.Parse(args)
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "%s", err.Error())
+	}
+	err = validateClusterID(f)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r, err := newVaultRole("", f.dryRun)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.Delete(vaultrole.DeleteConfig{ID: f.clusterID, Namespace: f.namespace, Organizations: splitList(f.organizations)})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func parseWriteFlags(name string, args []string) (flags, error) {
+	var f flags
+	fs := newFlagSet(name, &f)
+	registerClusterFlags(fs, &f)
+	registerOrganizationsFlag(fs, &f)
+	registerRoleFlags(fs, &f)
+	fs.BoolVar(&f.dryRun, "dry-run", false, "Only log what would be written.")
+	err := fs.Parse(args)
+	if err != nil {
+		return flags{}, microerror.Maskf(invalidFlagsError, "%s", err.Error())
+	}
+	err = validateClusterID(f)
+	if err != nil {
+		return flags{}, microerror.Mask(err)
+	}
+	if f.commonNameFormat == "" {
+		return flags{}, microerror.Maskf(invalidFlagsError, "-common-name-format must not be empty")
+	}
+
+	return f, nil
+}
@@ -0,0 +1,122 @@
+package vaultrole
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/giantswarm/microerror"
+)
+
+// CommonNameValues are the variables available to Config.CommonNameTemplate in
+// addition to the cluster ID. Values given with an operation config override
+// the ones given with Config.CommonNameValues, field by field.
+type CommonNameValues struct {
+	BaseDomain   string
+	Organization string
+	Region       string
+}
+
+// commonNameData is the data Config.CommonNameTemplate is executed with.
+type commonNameData struct {
+	BaseDomain   string
+	ClusterID    string
+	Organization string
+	Region       string
+}
+
+// commonName computes the common name of the given cluster ID. The given
+// format takes precedence over the configured format or template.
+func (r *VaultRole) commonName(format string, values CommonNameValues, ID string) (string, error) {
+	var commonName string
+	if format == "" && r.commonNameTemplate != nil {
+		values = mergeCommonNameValues(r.commonNameValues, values)
+
+		d := commonNameData{
+			BaseDomain:   values.BaseDomain,
+			ClusterID:    ID,
+			Organization: values.Organization,
+			Region:       values.Region,
+		}
+
+		var b bytes.Buffer
+		err := r.commonNameTemplate.Execute(&b, d)
+		if err != nil {
+			return "", microerror.Maskf(invalidConfigError, "config.CommonNameTemplate cannot be executed: %s", err.Error())
+		}
+		commonName = b.String()
+	} else {
+		if format == "" {
+			format = r.commonNameFormat
+		}
+		commonName = fmt.Sprintf(format, ID)
+	}
+
+	if commonName == "" || strings.Contains(commonName, ",") {
+		return "", microerror.Maskf(invalidConfigError, "common name %q must not be empty or contain commas", commonName)
+	}
+
+	return commonName, nil
+}
+
+func mergeCommonNameValues(defaults CommonNameValues, values CommonNameValues) CommonNameValues {
+	if values.BaseDomain == "" {
+		values.BaseDomain = defaults.BaseDomain
+	}
+	if values.Organization == "" {
+		values.Organization = defaults.Organization
+	}
+	if values.Region == "" {
+		values.Region = defaults.Region
+	}
+
+	return values
+}
+
+// parseCommonNameTemplate parses the given template and executes it once in
+// order to detect references to unknown variables.
+func parseCommonNameTemplate(text string) (*template.Template, error) {
+	t, err := template.New("commonName").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.CommonNameTemplate must be a valid template: %s", err.Error())
+	}
+
+	err = t.Execute(&bytes.Buffer{}, commonNameData{})
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.CommonNameTemplate must only use the variables BaseDomain, ClusterID, Organization and Region: %s", err.Error())
+	}
+
+	return t, nil
+}
+
+// validateCommonNameFormat ensures the given format contains exactly one %s
+// verb taking the cluster ID, so that formatting it does not produce garbage
+// like "%!(EXTRA string=al9qy)".
+func validateCommonNameFormat(format string) error {
+	var verbs int
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+
+		i++
+		if i == len(format) {
+			return microerror.Maskf(invalidConfigError, "common name format %q must not end with %%", format)
+		}
+
+		switch format[i] {
+		case '%':
+		case 's':
+			verbs++
+		default:
+			return microerror.Maskf(invalidConfigError, "common name format %q must only use the verb %%s", format)
+		}
+	}
+
+	if verbs != 1 {
+		return microerror.Maskf(invalidConfigError, "common name format %q must contain exactly one %%s verb, got %d", format, verbs)
+	}
+
+	return nil
+}
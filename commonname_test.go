@@ -0,0 +1,239 @@
+package vaultrole_test
+
+import (
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	vaultclient "github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/vaultrole"
+	"github.com/giantswarm/vaultrole/key"
+	"github.com/giantswarm/vaultrole/vaultroletest"
+)
+
+func Test_VaultRole_CommonName(t *testing.T) {
+	s := vaultroletest.NewServer()
+	defer s.Close()
+	s.Mount("", "pki-al9qy")
+
+	r, err := s.NewVaultRole(vaultrole.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Create(vaultrole.CreateConfig{ID: "al9qy", Organizations: []string{"api"}, TTL: "1h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Create(vaultrole.CreateConfig{CommonNameFormat: "api.%s.example.com", ID: "al9qy", Organizations: []string{"edge"}, TTL: "1h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	{
+		role, err := r.Search(vaultrole.SearchConfig{ID: "al9qy", Organizations: []string{"api"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if role.CommonName != "al9qy.g8s.gigantic.io" {
+			t.Fatalf("CommonName == %q, want %q", role.CommonName, "al9qy.g8s.gigantic.io")
+		}
+	}
+
+	{
+		d, _ := s.Data("", key.WriteRolePath("al9qy", []string{"edge"}))
+		if d["allowed_domains"].([]interface{})[0] != "api.al9qy.example.com" {
+			t.Fatalf("unexpected allowed domains %#v", d["allowed_domains"])
+		}
+
+		role, err := r.Search(vaultrole.SearchConfig{ID: "al9qy", Organizations: []string{"edge"}})
+		if !vaultrole.IsCommonNameMismatch(err) {
+			t.Fatalf("expected common name mismatch error got %#v", err)
+		}
+		if role.CommonName != "api.al9qy.example.com" {
+			t.Fatalf("CommonName == %q, want %q", role.CommonName, "api.al9qy.example.com")
+		}
+
+		_, err = r.Search(vaultrole.SearchConfig{CommonNameFormat: "api.%s.example.com", ID: "al9qy", Organizations: []string{"edge"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = r.Update(vaultrole.UpdateConfig{CommonNameFormat: "api.%s.example.com", ID: "al9qy", Organizations: []string{"edge"}, TTL: "2h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	{
+		role, err := r.Search(vaultrole.SearchConfig{CommonNameFormat: "api.%s.example.com", ID: "al9qy", Organizations: []string{"edge"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if role.CommonName != "api.al9qy.example.com" {
+			t.Fatalf("CommonName == %q, want %q", role.CommonName, "api.al9qy.example.com")
+		}
+	}
+}
+
+func Test_New_CommonNameFormat(t *testing.T) {
+	testCases := []struct {
+		name         string
+		format       string
+		template     string
+		errorMatcher func(error) bool
+	}{
+		{
+			name:   "case 0: format with one %s verb",
+			format: "%s.g8s.gigantic.io",
+		},
+		{
+			name:   "case 1: format with escaped percent sign",
+			format: "%s.g8s.gigantic.io%%",
+		},
+		{
+			name:         "case 2: format without verb",
+			format:       "g8s.gigantic.io",
+			errorMatcher: vaultrole.IsInvalidConfig,
+		},
+		{
+			name:         "case 3: format with two verbs",
+			format:       "%s.%s.gigantic.io",
+			errorMatcher: vaultrole.IsInvalidConfig,
+		},
+		{
+			name:         "case 4: format with unsupported verb",
+			format:       "%d.g8s.gigantic.io",
+			errorMatcher: vaultrole.IsInvalidConfig,
+		},
+		{
+			name:         "case 5: format with %v verb",
+			format:       "%v.g8s.gigantic.io",
+			errorMatcher: vaultrole.IsInvalidConfig,
+		},
+		{
+			name:     "case 6: template",
+			template: "{{.ClusterID}}.k8s.{{.Region}}.{{.BaseDomain}}",
+		},
+		{
+			name:         "case 7: template with unknown variable",
+			template:     "{{.ClusterID}}.{{.Zone}}",
+			errorMatcher: vaultrole.IsInvalidConfig,
+		},
+		{
+			name:         "case 8: format and template",
+			format:       "%s.g8s.gigantic.io",
+			template:     "{{.ClusterID}}.g8s.gigantic.io",
+			errorMatcher: vaultrole.IsInvalidConfig,
+		},
+		{
+			name:         "case 9: neither format nor template",
+			errorMatcher: vaultrole.IsInvalidConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := vaultrole.New(vaultrole.Config{
+				Logger:             microloggertest.New(),
+				VaultClient:        &vaultclient.Client{},
+				CommonNameFormat:   tc.format,
+				CommonNameTemplate: tc.template,
+			})
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+		})
+	}
+}
+
+func Test_VaultRole_CommonNameTemplate(t *testing.T) {
+	r, err := vaultrole.New(vaultrole.Config{
+		Logger:             microloggertest.New(),
+		VaultClient:        &vaultclient.Client{},
+		CommonNameTemplate: "{{.ClusterID}}.{{.Organization}}.k8s.{{.Region}}.{{.BaseDomain}}",
+		CommonNameValues: vaultrole.CommonNameValues{
+			BaseDomain: "gigantic.io",
+			Region:     "eu-central-1",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := r.Plan(vaultrole.PlanConfig{
+		AltNames:         []string{"api.internal"},
+		CommonNameValues: vaultrole.CommonNameValues{Organization: "acme", Region: "us-west-2"},
+		ID:               "al9qy",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "al9qy.acme.k8s.us-west-2.gigantic.io,api.internal"
+	if req.Payload.AllowedDomains != expected {
+		t.Fatalf("AllowedDomains == %q, want %q", req.Payload.AllowedDomains, expected)
+	}
+
+	_, err = r.Plan(vaultrole.PlanConfig{CommonNameFormat: "%s.%s", ID: "al9qy"})
+	if !vaultrole.IsInvalidConfig(err) {
+		t.Fatalf("expected invalid config error got %#v", err)
+	}
+}
+
+func Test_VaultRole_CommonNameFormat_Profile(t *testing.T) {
+	r, err := vaultrole.New(vaultrole.Config{
+		Logger:           microloggertest.New(),
+		VaultClient:      &vaultclient.Client{},
+		CommonNameFormat: "%s.default.io",
+		Profiles: map[string]vaultrole.Profile{
+			"api": {Organizations: []string{"api"}, TTL: "1h"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		config   vaultrole.PlanConfig
+		expected string
+	}{
+		{
+			name:     "case 0: default format without profile",
+			config:   vaultrole.PlanConfig{ID: "al9qy"},
+			expected: "al9qy.default.io",
+		},
+		{
+			name:     "case 1: overridden format without profile",
+			config:   vaultrole.PlanConfig{CommonNameFormat: "%s.custom.io", ID: "al9qy"},
+			expected: "al9qy.custom.io",
+		},
+		{
+			name:     "case 2: overridden format with profile",
+			config:   vaultrole.PlanConfig{CommonNameFormat: "%s.custom.io", ID: "al9qy", Profile: "api"},
+			expected: "al9qy.custom.io",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := r.Plan(tc.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if req.Payload.AllowedDomains != tc.expected {
+				t.Fatalf("AllowedDomains == %q, want %q", req.Payload.AllowedDomains, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ConditionReady is true in case the Vault role matches the spec.
+	ConditionReady = "Ready"
+)
+
+// CommonNameValues mirrors vaultrole.CommonNameValues.
+type CommonNameValues struct {
+	// +optional
+	BaseDomain string `json:"baseDomain,omitempty"`
+	// +optional
+	Organization string `json:"organization,omitempty"`
+	// +optional
+	Region string `json:"region,omitempty"`
+}
+
+// VaultRoleSpec mirrors vaultrole.CreateConfig.
+type VaultRoleSpec struct {
+	// ClusterID is the ID of the cluster owning the PKI backend.
+	// +kubebuilder:validation:MinLength=1
+	ClusterID string `json:"clusterID"`
+	// VaultNamespace is the Vault Enterprise namespace of the role.
+	// +optional
+	VaultNamespace string `json:"vaultNamespace,omitempty"`
+	// +optional
+	Organizations []string `json:"organizations,omitempty"`
+	// +optional
+	AltNames []string `json:"altNames,omitempty"`
+	// TTL of certificates issued by the role, e.g. 8640h.
+	// +optional
+	TTL string `json:"ttl,omitempty"`
+	// +optional
+	AllowBareDomains bool `json:"allowBareDomains,omitempty"`
+	// +optional
+	AllowSubdomains bool `json:"allowSubdomains,omitempty"`
+	// CommonNameFormat optionally overrides the common name format the
+	// controller is configured with.
+	// +optional
+	CommonNameFormat string `json:"commonNameFormat,omitempty"`
+	// CommonNameValues optionally override the common name values the
+	// controller is configured with.
+	// +optional
+	CommonNameValues CommonNameValues `json:"commonNameValues,omitempty"`
+	// Profile optionally references a profile the controller is configured
+	// with.
+	// +optional
+	Profile string `json:"profile,omitempty"`
+}
+
+// VaultRoleStatus mirrors vaultrole.Role as last written to Vault.
+type VaultRoleStatus struct {
+	// +optional
+	RoleName string `json:"roleName,omitempty"`
+	// +optional
+	ClusterID string `json:"clusterID,omitempty"`
+	// +optional
+	VaultNamespace string `json:"vaultNamespace,omitempty"`
+	// +optional
+	CommonName string `json:"commonName,omitempty"`
+	// +optional
+	Organizations []string `json:"organizations,omitempty"`
+	// +optional
+	AltNames []string `json:"altNames,omitempty"`
+	// +optional
+	TTL string `json:"ttl,omitempty"`
+	// +optional
+	AllowBareDomains bool `json:"allowBareDomains,omitempty"`
+	// +optional
+	AllowSubdomains bool `json:"allowSubdomains,omitempty"`
+	// ObservedGeneration is the generation of the spec last reconciled.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// VaultRole is a Vault PKI role managed by the vaultrole controller.
+//
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=`.spec.clusterID`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type VaultRole struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VaultRoleSpec   `json:"spec,omitempty"`
+	Status VaultRoleStatus `json:"status,omitempty"`
+}
+
+// VaultRoleList contains a list of VaultRole.
+//
+// +kubebuilder:object:root=true
+type VaultRoleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []VaultRole `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&VaultRole{}, &VaultRoleList{})
+}
@@ -0,0 +1,292 @@
+// Package controller provides a controller-runtime reconciler converging Vault
+// to VaultRole custom resources, see package api/v1alpha1. The package is a
+// separate Go module, so that users of vaultrole do not depend on the
+// Kubernetes libraries.
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/giantswarm/vaultrole"
+	"github.com/giantswarm/vaultrole/controller/api/v1alpha1"
+	"github.com/giantswarm/vaultrole/key"
+)
+
+// Finalizer is added to VaultRole custom resources, so that the Vault role can
+// be deleted before the custom resource is removed.
+const Finalizer = "vaultrole.giantswarm.io/finalizer"
+
+const (
+	reasonFailed     = "ReconciliationFailed"
+	reasonReconciled = "Reconciled"
+)
+
+type Config struct {
+	Client    client.Client
+	Logger    micrologger.Logger
+	VaultRole vaultrole.ManageInterface
+
+	// ResyncPeriod is the period after which a reconciled custom resource is
+	// reconciled again, in order to revert changes made to the role in Vault.
+	// Defaults to 5 minutes.
+	ResyncPeriod time.Duration
+}
+
+type Reconciler struct {
+	client    client.Client
+	logger    micrologger.Logger
+	vaultRole vaultrole.ManageInterface
+
+	resyncPeriod time.Duration
+}
+
+func New(config Config) (*Reconciler, error) {
+	if config.Client == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Client must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+	if config.VaultRole == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.VaultRole must not be empty")
+	}
+
+	if config.ResyncPeriod < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.ResyncPeriod must not be negative")
+	}
+	if config.ResyncPeriod == 0 {
+		config.ResyncPeriod = 5 * time.Minute
+	}
+
+	r := &Reconciler{
+		client:    config.Client,
+		logger:    config.Logger,
+		vaultRole: config.VaultRole,
+
+		resyncPeriod: config.ResyncPeriod,
+	}
+
+	return r, nil
+}
+
+// SetupWithManager registers the reconciler with the given manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	err := ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.VaultRole{}).
+		Complete(r)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// Reconcile converges the Vault role of the given custom resource to its spec
+// and reports the resulting role in the status. Custom resources being
+// deleted get their Vault role deleted before the finalizer is removed.
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var cr v1alpha1.VaultRole
+	err := r.client.Get(ctx, req.NamespacedName, &cr)
+	if apierrors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		return ctrl.Result{}, microerror.Mask(err)
+	}
+
+	if !cr.DeletionTimestamp.IsZero() {
+		err = r.delete(ctx, &cr)
+		if err != nil {
+			return ctrl.Result{}, microerror.Mask(err)
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	if !controllerutil.ContainsFinalizer(&cr, Finalizer) {
+		controllerutil.AddFinalizer(&cr, Finalizer)
+		err = r.client.Update(ctx, &cr)
+		if err != nil {
+			return ctrl.Result{}, microerror.Mask(err)
+		}
+	}
+
+	ensureErr := r.ensure(ctx, &cr)
+	if ensureErr != nil {
+		r.logger.LogCtx(ctx, "level", "error", "message", "failed to reconcile Vault role", "name", req.String(), "stack", microerror.JSON(ensureErr))
+
+		meta.SetStatusCondition(&cr.Status.Conditions, metav1.Condition{
+			Type:               v1alpha1.ConditionReady,
+			Status:             metav1.ConditionFalse,
+			Reason:             reasonFailed,
+			Message:            ensureErr.Error(),
+			ObservedGeneration: cr.Generation,
+		})
+	} else {
+		meta.SetStatusCondition(&cr.Status.Conditions, metav1.Condition{
+			Type:               v1alpha1.ConditionReady,
+			Status:             metav1.ConditionTrue,
+			Reason:             reasonReconciled,
+			Message:            "Vault role matches the spec",
+			ObservedGeneration: cr.Generation,
+		})
+		cr.Status.ObservedGeneration = cr.Generation
+	}
+
+	err = r.client.Status().Update(ctx, &cr)
+	if err != nil {
+		return ctrl.Result{}, microerror.Mask(err)
+	}
+
+	if ensureErr != nil {
+		return ctrl.Result{}, microerror.Mask(ensureErr)
+	}
+
+	return ctrl.Result{RequeueAfter: r.resyncPeriod}, nil
+}
+
+// ensure creates or updates the Vault role of the given custom resource and
+// updates its status accordingly. In case the spec now identifies another
+// role, the role previously managed is deleted, unless it is still used, see
+// deleteRole.
+func (r *Reconciler) ensure(ctx context.Context, cr *v1alpha1.VaultRole) error {
+	c := vaultrole.EnsureConfig{
+		AllowBareDomains: cr.Spec.AllowBareDomains,
+		AllowSubdomains:  cr.Spec.AllowSubdomains,
+		AltNames:         cr.Spec.AltNames,
+		CommonNameFormat: cr.Spec.CommonNameFormat,
+		CommonNameValues: vaultrole.CommonNameValues(cr.Spec.CommonNameValues),
+		ID:               cr.Spec.ClusterID,
+		Namespace:        cr.Spec.VaultNamespace,
+		Organizations:    append([]string(nil), cr.Spec.Organizations...),
+		TTL:              cr.Spec.TTL,
+		Profile:          cr.Spec.Profile,
+	}
+
+	results, err := r.vaultRole.EnsureMany([]vaultrole.EnsureConfig{c})
+	if len(results) == 1 && results[0].Error != nil {
+		return microerror.Mask(results[0].Error)
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+	roleName := results[0].RoleName
+
+	// The role is looked up using the organizations of the result, since they
+	// might be defined by a profile.
+	role, err := r.vaultRole.Search(vaultrole.SearchConfig{
+		CommonNameFormat: c.CommonNameFormat,
+		CommonNameValues: c.CommonNameValues,
+		ID:               c.ID,
+		Namespace:        c.Namespace,
+		Organizations:    append([]string(nil), results[0].Organizations...),
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	previous := cr.Status
+	moved := previous.RoleName != "" && (previous.RoleName != roleName || previous.ClusterID != c.ID || previous.VaultNamespace != c.Namespace)
+
+	cr.Status.RoleName = roleName
+	cr.Status.ClusterID = c.ID
+	cr.Status.VaultNamespace = c.Namespace
+	cr.Status.CommonName = role.CommonName
+	cr.Status.Organizations = role.Organizations
+	cr.Status.AltNames = role.AltNames
+	cr.Status.TTL = role.TTL.String()
+	cr.Status.AllowBareDomains = role.AllowBareDomains
+	cr.Status.AllowSubdomains = role.AllowSubdomains
+
+	if moved {
+		err := r.deleteRole(ctx, cr, previous.RoleName, previous.ClusterID, previous.VaultNamespace, previous.Organizations)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// delete deletes the Vault role of the given custom resource, unless it is
+// still used, see deleteRole, and removes the finalizer.
+func (r *Reconciler) delete(ctx context.Context, cr *v1alpha1.VaultRole) error {
+	if !controllerutil.ContainsFinalizer(cr, Finalizer) {
+		return nil
+	}
+
+	// The status identifies the role last written. In case the custom
+	// resource never got reconciled successfully, the spec is used instead.
+	if cr.Status.RoleName != "" {
+		err := r.deleteRole(ctx, cr, cr.Status.RoleName, cr.Status.ClusterID, cr.Status.VaultNamespace, cr.Status.Organizations)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	} else if cr.Spec.ClusterID != "" {
+		roleName := key.RoleName(cr.Spec.ClusterID, append([]string(nil), cr.Spec.Organizations...))
+		err := r.deleteRole(ctx, cr, roleName, cr.Spec.ClusterID, cr.Spec.VaultNamespace, cr.Spec.Organizations)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	controllerutil.RemoveFinalizer(cr, Finalizer)
+	err := r.client.Update(ctx, cr)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// deleteRole deletes the given Vault role previously managed by the given
+// custom resource. The base role of a cluster, see key.RoleName, is never
+// deleted, since it is used by the cluster regardless of any custom resource.
+// Roles which other custom resources report in their status are kept as well.
+func (r *Reconciler) deleteRole(ctx context.Context, cr *v1alpha1.VaultRole, roleName string, ID string, namespace string, organizations []string) error {
+	if roleName == key.RoleName(ID, nil) {
+		r.logger.LogCtx(ctx, "level", "debug", "message", "keeping base Vault role", "role", roleName, "clusterID", ID)
+		return nil
+	}
+
+	{
+		var list v1alpha1.VaultRoleList
+		err := r.client.List(ctx, &list)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		for _, o := range list.Items {
+			if o.Namespace == cr.Namespace && o.Name == cr.Name {
+				continue
+			}
+			if o.Status.RoleName == roleName && o.Status.ClusterID == ID && o.Status.VaultNamespace == namespace {
+				r.logger.LogCtx(ctx, "level", "debug", "message", "keeping Vault role used by another custom resource", "role", roleName, "clusterID", ID, "name", o.Namespace+"/"+o.Name)
+				return nil
+			}
+		}
+	}
+
+	c := vaultrole.DeleteConfig{
+		ID:            ID,
+		Namespace:     namespace,
+		Organizations: append([]string(nil), organizations...),
+	}
+
+	err := r.vaultRole.Delete(c)
+	if vaultrole.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
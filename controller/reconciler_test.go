@@ -0,0 +1,322 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/vaultrole"
+	"github.com/giantswarm/vaultrole/controller/api/v1alpha1"
+	"github.com/giantswarm/vaultrole/key"
+	"github.com/giantswarm/vaultrole/vaultroletest"
+)
+
+func Test_Reconciler(t *testing.T) {
+	ctx := context.Background()
+
+	s := vaultroletest.NewServer()
+	defer s.Close()
+	s.Mount("", "pki-al9qy")
+
+	vr, err := s.NewVaultRole(vaultrole.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scheme := runtime.NewScheme()
+	err = v1alpha1.AddToScheme(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := types.NamespacedName{Namespace: "default", Name: "api"}
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithStatusSubresource(&v1alpha1.VaultRole{}).
+		WithObjects(&v1alpha1.VaultRole{
+			ObjectMeta: metav1.ObjectMeta{Namespace: name.Namespace, Name: name.Name},
+			Spec: v1alpha1.VaultRoleSpec{
+				ClusterID:     "al9qy",
+				Organizations: []string{"api"},
+				AltNames:      []string{"kubernetes"},
+				TTL:           "1h",
+			},
+		}).
+		Build()
+
+	r, err := New(Config{
+		Client:    k8sClient,
+		Logger:    microloggertest.New(),
+		VaultRole: vr,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reconcile := func() error {
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: name})
+		return err
+	}
+	get := func() *v1alpha1.VaultRole {
+		var cr v1alpha1.VaultRole
+		err := k8sClient.Get(ctx, name, &cr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &cr
+	}
+	exists := func(organizations []string) bool {
+		_, ok := s.Data("", key.WriteRolePath("al9qy", organizations))
+		return ok
+	}
+
+	// The role is created and the status reflects it.
+	{
+		err := reconcile()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cr := get()
+		if len(cr.Finalizers) != 1 || cr.Finalizers[0] != Finalizer {
+			t.Fatalf("expected finalizer got %v", cr.Finalizers)
+		}
+		if !meta.IsStatusConditionTrue(cr.Status.Conditions, v1alpha1.ConditionReady) {
+			t.Fatalf("expected ready condition got %#v", cr.Status.Conditions)
+		}
+		if cr.Status.RoleName != key.RoleName("al9qy", []string{"api"}) || cr.Status.CommonName != "al9qy.g8s.gigantic.io" || cr.Status.TTL != "1h0m0s" {
+			t.Fatalf("unexpected status %#v", cr.Status)
+		}
+		if !exists([]string{"api"}) {
+			t.Fatalf("expected role to exist")
+		}
+	}
+
+	// Changing the organizations moves the role.
+	{
+		cr := get()
+		cr.Spec.Organizations = []string{"api", "system:masters"}
+		err := k8sClient.Update(ctx, cr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = reconcile()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if exists([]string{"api"}) {
+			t.Fatalf("expected previous role to be deleted")
+		}
+		if !exists([]string{"api", "system:masters"}) {
+			t.Fatalf("expected role to exist")
+		}
+	}
+
+	// Invalid specs are reported in the status.
+	{
+		cr := get()
+		cr.Spec.TTL = "forever"
+		err := k8sClient.Update(ctx, cr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = reconcile()
+		if !vaultrole.IsInvalidConfig(err) {
+			t.Fatalf("expected invalid config error got %#v", err)
+		}
+
+		cr = get()
+		c := meta.FindStatusCondition(cr.Status.Conditions, v1alpha1.ConditionReady)
+		if c == nil || c.Status != metav1.ConditionFalse || c.Reason != reasonFailed {
+			t.Fatalf("unexpected ready condition %#v", c)
+		}
+		if !strings.Contains(c.Message, "forever") {
+			t.Fatalf("expected message to explain the invalid TTL got %q", c.Message)
+		}
+
+		cr.Spec.TTL = "2h"
+		err = k8sClient.Update(ctx, cr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = reconcile()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Deleting the custom resource deletes the role.
+	{
+		err := k8sClient.Delete(ctx, get())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = reconcile()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if exists([]string{"api", "system:masters"}) {
+			t.Fatalf("expected role to be deleted")
+		}
+
+		var cr v1alpha1.VaultRole
+		err = k8sClient.Get(ctx, name, &cr)
+		if client.IgnoreNotFound(err) != nil || err == nil {
+			t.Fatalf("expected custom resource to be removed got %#v", err)
+		}
+
+		err = reconcile()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func Test_Reconciler_KeepRoles(t *testing.T) {
+	testCases := []struct {
+		name          string
+		organizations []string
+		others        []v1alpha1.VaultRole
+		move          bool
+		expectKept    bool
+	}{
+		{
+			name:          "case 0: moved role is deleted",
+			organizations: []string{"api"},
+			move:          true,
+			expectKept:    false,
+		},
+		{
+			name:          "case 1: moved base role is kept",
+			organizations: nil,
+			move:          true,
+			expectKept:    true,
+		},
+		{
+			name:          "case 2: moved role used by another custom resource is kept",
+			organizations: []string{"api"},
+			others: []v1alpha1.VaultRole{
+				{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "api"},
+					Spec:       v1alpha1.VaultRoleSpec{ClusterID: "al9qy", Organizations: []string{"api"}},
+					Status:     v1alpha1.VaultRoleStatus{RoleName: key.RoleName("al9qy", []string{"api"}), ClusterID: "al9qy"},
+				},
+			},
+			move:       true,
+			expectKept: true,
+		},
+		{
+			name:          "case 3: deleted base role is kept",
+			organizations: nil,
+			expectKept:    true,
+		},
+		{
+			name:          "case 4: deleted role used by another custom resource is kept",
+			organizations: []string{"api"},
+			others: []v1alpha1.VaultRole{
+				{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "api"},
+					Spec:       v1alpha1.VaultRoleSpec{ClusterID: "al9qy", Organizations: []string{"api"}},
+					Status:     v1alpha1.VaultRoleStatus{RoleName: key.RoleName("al9qy", []string{"api"}), ClusterID: "al9qy"},
+				},
+			},
+			expectKept: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			s := vaultroletest.NewServer()
+			defer s.Close()
+			s.Mount("", "pki-al9qy")
+
+			vr, err := s.NewVaultRole(vaultrole.Config{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			scheme := runtime.NewScheme()
+			err = v1alpha1.AddToScheme(scheme)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			name := types.NamespacedName{Namespace: "default", Name: "api"}
+			objects := []client.Object{
+				&v1alpha1.VaultRole{
+					ObjectMeta: metav1.ObjectMeta{Namespace: name.Namespace, Name: name.Name},
+					Spec: v1alpha1.VaultRoleSpec{
+						ClusterID:     "al9qy",
+						Organizations: tc.organizations,
+						TTL:           "1h",
+					},
+				},
+			}
+			for i := range tc.others {
+				objects = append(objects, &tc.others[i])
+			}
+
+			k8sClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithStatusSubresource(&v1alpha1.VaultRole{}).
+				WithObjects(objects...).
+				Build()
+
+			r, err := New(Config{
+				Client:    k8sClient,
+				Logger:    microloggertest.New(),
+				VaultRole: vr,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: name})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var cr v1alpha1.VaultRole
+			err = k8sClient.Get(ctx, name, &cr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.move {
+				cr.Spec.Organizations = []string{"etcd"}
+				err = k8sClient.Update(ctx, &cr)
+			} else {
+				err = k8sClient.Delete(ctx, &cr)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: name})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, kept := s.Data("", key.WriteRolePath("al9qy", tc.organizations))
+			if kept != tc.expectKept {
+				t.Fatalf("role kept == %t, want %t", kept, tc.expectKept)
+			}
+		})
+	}
+}
@@ -5,10 +5,32 @@ import (
 )
 
 func (r *VaultRole) Create(config CreateConfig) error {
+	// The profile has to be merged first, since it might define the
+	// organizations, which determine the role.
+	merged, err := r.mergeProfile(writeConfig(config))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	config = CreateConfig(merged)
+
+	p := lockPath(config.Namespace, config.ID, config.Organizations)
+
+	err = r.locker.Do("create", p, config, func() error {
+		return r.create(config)
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (r *VaultRole) create(config CreateConfig) error {
 	// Check if the requested role exists.
 	{
 		c := ExistsConfig{
 			ID:            config.ID,
+			Namespace:     config.Namespace,
 			Organizations: config.Organizations,
 		}
 		exists, err := r.Exists(c)
@@ -16,6 +38,15 @@ func (r *VaultRole) Create(config CreateConfig) error {
 			return microerror.Mask(err)
 		}
 		if exists {
+			// The policy of an existing role is reconciled anyway, so that
+			// retrying a Create which failed after writing the role, or
+			// enabling Config.ManagePolicies for existing roles, cannot leave
+			// roles without policy.
+			err = r.writePolicy(config.Namespace, config.ID, config.Organizations)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
 			return microerror.Maskf(alreadyExistsError, config.ID)
 		}
 	}
@@ -28,6 +59,8 @@ func (r *VaultRole) Create(config CreateConfig) error {
 		if err != nil {
 			return microerror.Mask(err)
 		}
+
+		r.audit(AuditOperationCreate, c, nil)
 	}
 
 	return nil
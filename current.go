@@ -1,48 +1,92 @@
 package vaultrole
 
 import (
-	"encoding/json"
-
 	"github.com/giantswarm/microerror"
 	"github.com/hashicorp/vault/api"
-	"github.com/hashicorp/vault/sdk/helper/parseutil"
 
 	"github.com/giantswarm/vaultrole/key"
 )
 
 func (r *VaultRole) Exists(config ExistsConfig) (bool, error) {
-	// Check if a PKI for the given cluster ID exists.
-	secret, err := r.vaultClient.Logical().List(key.ListRolesPath(config.ID))
-	if IsNoVaultHandlerDefined(err) {
-		return false, nil
-	} else if err != nil {
+	names, err := r.listRoleNames(config.Namespace, config.ID)
+	if err != nil {
 		return false, microerror.Mask(err)
 	}
 
-	// In case there is not a single role for this PKI backend, secret is nil.
-	if secret == nil {
-		return false, nil
-	}
-
 	// When listing roles a list of role names is returned. Here we iterate over
 	// this list and if we find the desired role name, it means the role has
 	// already been created.
-	if keys, ok := secret.Data["keys"]; ok {
-		if list, ok := keys.([]interface{}); ok {
-			for _, k := range list {
-				if str, ok := k.(string); ok && str == key.RoleName(config.ID, config.Organizations) {
-					return true, nil
-				}
-			}
+	for _, n := range names {
+		if n == key.RoleName(config.ID, config.Organizations) {
+			return true, nil
 		}
 	}
 
 	return false, nil
 }
 
+func (r *VaultRole) List(config ListConfig) ([]NamedRole, error) {
+	names, err := r.listRoleNames(config.Namespace, config.ID)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var roles []NamedRole
+	for _, n := range names {
+		role, err := r.readRole(config.Namespace, config.ID, key.NamedRolePath(config.ID, n))
+		if IsNotFound(err) {
+			// The role got deleted after listing it.
+			continue
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		roles = append(roles, NamedRole{Name: n, Role: role})
+	}
+
+	return roles, nil
+}
+
+// Search returns the role of the given cluster ID and organizations. In case
+// the common name of the role does not match the one computed using the
+// configured common name format, the role is returned along with a
+// commonNameMismatchError.
 func (r *VaultRole) Search(config SearchConfig) (Role, error) {
+	role, err := r.readRole(config.Namespace, config.ID, key.ReadRolePath(config.ID, config.Organizations))
+	if err != nil {
+		return Role{}, microerror.Mask(err)
+	}
+
+	if config.CommonNameFormat != "" {
+		err := validateCommonNameFormat(config.CommonNameFormat)
+		if err != nil {
+			return Role{}, microerror.Mask(err)
+		}
+	}
+	expected, err := r.commonName(config.CommonNameFormat, config.CommonNameValues, config.ID)
+	if err != nil {
+		return Role{}, microerror.Mask(err)
+	}
+	if role.CommonName != expected {
+		return role, microerror.Maskf(commonNameMismatchError, "Vault role of cluster '%s' has common name '%s', expected '%s'", config.ID, role.CommonName, expected)
+	}
+
+	return role, nil
+}
+
+// readRole reads and parses the role at the given path.
+func (r *VaultRole) readRole(namespace string, ID string, path string) (Role, error) {
 	// Check if a PKI for the given cluster ID exists.
-	secret, err := r.vaultClient.Logical().Read(key.ReadRolePath(config.ID, config.Organizations))
+	var secret *api.Secret
+	err := r.withReauth(func() error {
+		var err error
+		secret, err = r.logicalClient.Read(r.ctx, namespace, path)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		return nil
+	})
 	if IsNoVaultHandlerDefined(err) {
 		return Role{}, microerror.Maskf(notFoundError, "no vault handler defined")
 	} else if err != nil {
@@ -51,7 +95,7 @@ func (r *VaultRole) Search(config SearchConfig) (Role, error) {
 
 	// In case there is not a single role for this PKI backend, secret is nil.
 	if secret == nil {
-		return Role{}, microerror.Maskf(notFoundError, "no vault secret at path '%s'", key.RoleName(config.ID, config.Organizations))
+		return Role{}, microerror.Maskf(notFoundError, "no vault secret at path '%s'", path)
 	}
 
 	role, err := vaultSecretToRole(secret)
@@ -59,118 +103,67 @@ func (r *VaultRole) Search(config SearchConfig) (Role, error) {
 		return Role{}, microerror.Mask(err)
 	}
 
-	role.ID = config.ID
+	role.ID = ID
 	return role, nil
 }
 
-// vaultSecretToRole makes required type casts / type checks and parsing to
-// extract role information from Vault api.Secret.
-func vaultSecretToRole(secret *api.Secret) (Role, error) {
-	var role Role
-
-	{
-		v, exists := secret.Data["allow_bare_domains"]
-		if !exists {
-			return Role{}, microerror.Maskf(invalidVaultResponseError, "allow_bare_domains missing from Vault api.Secret.Data")
-		}
-
-		allowBareDomains, ok := v.(bool)
-		if !ok {
-			return Role{}, microerror.Maskf(invalidVaultResponseError, "Vault secret.Data[\"allow_bare_domains\"] type is %T, expected %T", secret.Data["allow_bare_domains"], allowBareDomains)
+// listRoleNames returns the names of all roles of the PKI backend of the given
+// cluster ID within the given namespace. In case the PKI backend or any role
+// does not exist, an empty list is returned.
+func (r *VaultRole) listRoleNames(namespace string, ID string) ([]string, error) {
+	// Check if a PKI for the given cluster ID exists.
+	var secret *api.Secret
+	err := r.withReauth(func() error {
+		var err error
+		secret, err = r.logicalClient.List(r.ctx, namespace, key.ListRolesPath(ID))
+		if err != nil {
+			return microerror.Mask(err)
 		}
 
-		role.AllowBareDomains = allowBareDomains
+		return nil
+	})
+	if IsNoVaultHandlerDefined(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
 	}
 
-	{
-		v, exists := secret.Data["allow_subdomains"]
-		if !exists {
-			return Role{}, microerror.Maskf(invalidVaultResponseError, "allow_subdomains missing from Vault api.Secret.Data")
-		}
-
-		allowSubdomains, ok := v.(bool)
-		if !ok {
-			return Role{}, microerror.Maskf(invalidVaultResponseError, "Vault secret.Data[\"allow_subdomains\"] type is %T, expected %T", secret.Data["allow_subdomains"], allowSubdomains)
-		}
-
-		role.AllowSubdomains = allowSubdomains
+	// In case there is not a single role for this PKI backend, secret is nil.
+	if secret == nil {
+		return nil, nil
 	}
 
-	// List types in Vault were earlier joined with comma to single
-	// concatenated string. Now they are slice of interfaces which are strings
-	// underneath.
-	{
-		allowedDomains, exists := secret.Data["allowed_domains"]
-		if !exists {
-			return Role{}, microerror.Maskf(invalidVaultResponseError, "allowed_domains missing from Vault api.Secret.Data")
-		}
-
-		switch v := allowedDomains.(type) {
-		case string:
-			role.AltNames = key.ToAltNames(v)
-		case []string:
-			role.AltNames = v[1:]
-		case []interface{}:
-			allowedDomains := make([]string, 0, len(v))
-			for i, val := range v {
-				if s, ok := val.(string); ok {
-					allowedDomains = append(allowedDomains, s)
-				} else {
-					return Role{}, microerror.Maskf(invalidVaultResponseError, "Vault secret.Data[\"allowed_domains\"][%d] has unexpected type '%T'. It's not string nor []string.", i, val)
+	var names []string
+	if keys, ok := secret.Data["keys"]; ok {
+		if list, ok := keys.([]interface{}); ok {
+			for _, k := range list {
+				if str, ok := k.(string); ok {
+					names = append(names, str)
 				}
 			}
-
-			// TODO: Why first one is dropped (this was in key.ToAltNames()?
-			role.AltNames = allowedDomains[1:]
-		default:
-			return Role{}, microerror.Maskf(invalidVaultResponseError, "Vault secret.Data[\"allowed_domains\"] type is '%T'. It's not string, []string nor []interface{} (masking strings).", secret.Data["allowed_domains"])
 		}
 	}
 
-	{
-		organizations, exists := secret.Data["organization"]
-		if !exists {
-			return Role{}, microerror.Maskf(invalidVaultResponseError, "organization missing from Vault api.Secret.Data")
-		}
-
-		switch v := organizations.(type) {
-		case string:
-			role.Organizations = key.ToOrganizations(v)
-		case []string:
-			role.Organizations = v
-		case []interface{}:
-			organizations := make([]string, 0, len(v))
-			for i, val := range v {
-				if s, ok := val.(string); ok {
-					organizations = append(organizations, s)
-				} else {
-					return Role{}, microerror.Maskf(invalidVaultResponseError, "Vault secret.Data[\"organization\"][%d] has unexpected type '%T'. It's not string nor []string.", i, val)
-				}
-			}
+	return names, nil
+}
 
-			role.Organizations = organizations
-		default:
-			return Role{}, microerror.Maskf(invalidVaultResponseError, "Vault secret.Data[\"organization\"] type is '%T'. It's not string, []string nor []interface{} (masking strings).", secret.Data["organization"])
-		}
+// vaultSecretToRole decodes the given Vault api.Secret according to
+// roleSchema. Fields returned by Vault which are not part of the schema are
+// kept in Role.Extra.
+func vaultSecretToRole(secret *api.Secret) (Role, error) {
+	role, err := roleSchema.decode(secret.Data)
+	if err != nil {
+		return Role{}, microerror.Mask(err)
 	}
 
-	{
-		v, exists := secret.Data["ttl"]
-		if !exists {
-			return Role{}, microerror.Maskf(invalidVaultResponseError, "ttl missing from Vault api.Secret.Data")
+	for k, v := range secret.Data {
+		if roleSchema.has(k) {
+			continue
 		}
-
-		ttlStr, ok := v.(json.Number)
-		if !ok {
-			return Role{}, microerror.Maskf(invalidVaultResponseError, "Vault secret.Data[\"ttl\"] type is %T, expected %T", secret.Data["ttl"], ttlStr)
+		if role.Extra == nil {
+			role.Extra = map[string]interface{}{}
 		}
-
-		ttl, err := parseutil.ParseDurationSecond(ttlStr.String())
-		if err != nil {
-			return Role{}, microerror.Mask(err)
-		}
-
-		role.TTL = ttl
+		role.Extra[k] = v
 	}
 
 	return role, nil
@@ -31,6 +31,7 @@ func Test_vaultSecretToRole(t *testing.T) {
 				AllowBareDomains: true,
 				AllowSubdomains:  true,
 				AltNames:         []string{"bar.com", "baz.com"},
+				CommonName:       "foo.com",
 				Organizations:    []string{"Foobar"},
 				TTL:              3600 * time.Second,
 			},
@@ -51,6 +52,7 @@ func Test_vaultSecretToRole(t *testing.T) {
 				AllowBareDomains: true,
 				AllowSubdomains:  true,
 				AltNames:         []string{"bar.com", "baz.com"},
+				CommonName:       "foo.com",
 				Organizations:    []string{"Foobar"},
 				TTL:              3600 * time.Second,
 			},
@@ -71,6 +73,7 @@ func Test_vaultSecretToRole(t *testing.T) {
 				AllowBareDomains: true,
 				AllowSubdomains:  true,
 				AltNames:         []string{"bar.com", "baz.com"},
+				CommonName:       "foo.com",
 				Organizations:    []string{"Foobar"},
 				TTL:              3600 * time.Second,
 			},
@@ -240,6 +243,7 @@ func Test_vaultSecretToRole(t *testing.T) {
 				AllowBareDomains: true,
 				AllowSubdomains:  true,
 				AltNames:         []string{"bar.com", "baz.com"},
+				CommonName:       "foo.com",
 				Organizations:    []string{"Foo", "Bar", "Baz"},
 				TTL:              3600 * time.Second,
 			},
@@ -260,11 +264,91 @@ func Test_vaultSecretToRole(t *testing.T) {
 				AllowBareDomains: true,
 				AllowSubdomains:  true,
 				AltNames:         []string{"bar.com", "baz.com"},
+				CommonName:       "foo.com",
 				Organizations:    []string{"Foo", "Bar", "Baz"},
 				TTL:              3600 * time.Second,
 			},
 			errorMatcher: nil,
 		},
+		{
+			name: "case 15: test empty allowed_domains as slice of interfaces",
+			input: &api.Secret{
+				Data: map[string]interface{}{
+					"allow_bare_domains": false,
+					"allow_subdomains":   false,
+					"allowed_domains":    []interface{}{},
+					"organization":       []interface{}{},
+					"ttl":                json.Number("3600"),
+				},
+			},
+			expectedRole: Role{
+				AltNames:      []string{},
+				Organizations: []string{},
+				TTL:           3600 * time.Second,
+			},
+			errorMatcher: nil,
+		},
+		{
+			name: "case 16: test empty allowed_domains as slice of string",
+			input: &api.Secret{
+				Data: map[string]interface{}{
+					"allow_bare_domains": false,
+					"allow_subdomains":   false,
+					"allowed_domains":    []string{},
+					"organization":       "",
+					"ttl":                json.Number("3600"),
+				},
+			},
+			expectedRole: Role{
+				AltNames: []string{},
+				TTL:      3600 * time.Second,
+			},
+			errorMatcher: nil,
+		},
+		{
+			name: "case 17: test single allowed domain is kept as common name",
+			input: &api.Secret{
+				Data: map[string]interface{}{
+					"allow_bare_domains": false,
+					"allow_subdomains":   false,
+					"allowed_domains":    []interface{}{"foo.com"},
+					"organization":       []interface{}{"Foo"},
+					"ttl":                json.Number("3600"),
+				},
+			},
+			expectedRole: Role{
+				AltNames:      []string{},
+				CommonName:    "foo.com",
+				Organizations: []string{"Foo"},
+				TTL:           3600 * time.Second,
+			},
+			errorMatcher: nil,
+		},
+		{
+			name: "case 18: test unrecognised fields are kept in extra",
+			input: &api.Secret{
+				Data: map[string]interface{}{
+					"allow_bare_domains": false,
+					"allow_subdomains":   false,
+					"allowed_domains":    []interface{}{"foo.com", "bar.com"},
+					"key_type":           "rsa",
+					"max_ttl":            json.Number("7200"),
+					"organization":       []interface{}{"Foo"},
+					"ttl":                json.Number("3600"),
+				},
+			},
+			expectedRole: Role{
+				AltNames:      []string{"bar.com"},
+				CommonName:    "foo.com",
+				Organizations: []string{"Foo"},
+				TTL:           3600 * time.Second,
+				Extra: map[string]interface{}{
+					"key_type": "rsa",
+					"max_ttl":  json.Number("7200"),
+				},
+			},
+			errorMatcher: nil,
+		},
 	}
 
 	for _, tc := range testCases {
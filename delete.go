@@ -0,0 +1,83 @@
+package vaultrole
+
+import (
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/vaultrole/key"
+)
+
+func (r *VaultRole) Delete(config DeleteConfig) error {
+	p := lockPath(config.Namespace, config.ID, config.Organizations)
+
+	err := r.locker.Do("delete", p, config, func() error {
+		return r.delete(config)
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (r *VaultRole) delete(config DeleteConfig) error {
+	// Check if the requested role exists.
+	{
+		c := ExistsConfig{
+			ID:            config.ID,
+			Namespace:     config.Namespace,
+			Organizations: config.Organizations,
+		}
+		exists, err := r.Exists(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		if !exists {
+			// The policy is deleted anyway, so that retrying a Delete which
+			// failed after deleting the role cannot leave the policy behind.
+			err = r.deletePolicy(config.Namespace, config.ID, config.Organizations)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			return microerror.Maskf(notFoundError, "cannot delete Vault role '%s'", config.ID)
+		}
+	}
+
+	// Delete the requested role if it exists.
+	{
+		previous, err := r.previousRole(config.Namespace, config.ID, config.Organizations)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		k := key.WriteRolePath(config.ID, config.Organizations)
+
+		// The policy is deleted before the role, so that a failure leaves the
+		// role in place and Delete can be retried.
+		err = r.deletePolicy(config.Namespace, config.ID, config.Organizations)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		if r.dryRun {
+			r.logger.Log("level", "info", "message", "dry run, skipping delete of Vault role", "namespace", config.Namespace, "path", k)
+			return nil
+		}
+
+		err = r.withReauth(func() error {
+			_, err := r.logicalClient.Delete(r.ctx, config.Namespace, k)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		r.emit(AuditOperationDelete, config.Namespace, config.ID, config.Organizations, previous, nil)
+	}
+
+	return nil
+}
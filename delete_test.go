@@ -0,0 +1,69 @@
+package vaultrole_test
+
+import (
+	"testing"
+
+	"github.com/giantswarm/vaultrole"
+	"github.com/giantswarm/vaultrole/vaultroletest"
+)
+
+func Test_VaultRole_ListDelete(t *testing.T) {
+	s := vaultroletest.NewServer()
+	defer s.Close()
+	s.Mount("", "pki-al9qy")
+
+	sink := &testAuditSink{}
+
+	r, err := s.NewVaultRole(vaultrole.Config{AuditSink: sink})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, orgs := range [][]string{nil, {"api", "system:masters"}} {
+		err = r.Create(vaultrole.CreateConfig{ID: "al9qy", Organizations: orgs, TTL: "1h"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	{
+		roles, err := r.List(vaultrole.ListConfig{ID: "al9qy"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(roles) != 2 {
+			t.Fatalf("expected 2 roles got %d", len(roles))
+		}
+		if roles[0].Name != "role-al9qy" || len(roles[0].Role.Organizations) != 0 {
+			t.Fatalf("unexpected role %#v", roles[0])
+		}
+		if len(roles[1].Role.Organizations) != 2 {
+			t.Fatalf("unexpected role %#v", roles[1])
+		}
+	}
+
+	err = r.Delete(vaultrole.DeleteConfig{ID: "al9qy", Organizations: []string{"system:masters", "api"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	{
+		exists, err := r.Exists(vaultrole.ExistsConfig{ID: "al9qy", Organizations: []string{"api", "system:masters"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exists {
+			t.Fatalf("expected role to be deleted")
+		}
+	}
+
+	err = r.Delete(vaultrole.DeleteConfig{ID: "al9qy", Organizations: []string{"api", "system:masters"}})
+	if !vaultrole.IsNotFound(err) {
+		t.Fatalf("expected not found error got %#v", err)
+	}
+
+	e := sink.events[len(sink.events)-1]
+	if e.Operation != vaultrole.AuditOperationDelete || e.Previous == nil || e.Current != nil {
+		t.Fatalf("unexpected delete event %#v", e)
+	}
+}
@@ -0,0 +1,58 @@
+package vaultrole_test
+
+import (
+	"testing"
+
+	"github.com/giantswarm/vaultrole"
+	"github.com/giantswarm/vaultrole/key"
+	"github.com/giantswarm/vaultrole/vaultroletest"
+)
+
+func Test_VaultRole_DryRun(t *testing.T) {
+	s := vaultroletest.NewServer()
+	defer s.Close()
+	s.Mount("", "pki-al9qy")
+
+	r, err := s.NewVaultRole(vaultrole.Config{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name         string
+		config       vaultrole.CreateConfig
+		errorMatcher func(error) bool
+	}{
+		{
+			name:   "case 0: valid config",
+			config: vaultrole.CreateConfig{ID: "al9qy", Organizations: []string{"api"}, TTL: "1h"},
+		},
+		{
+			name:         "case 1: invalid TTL",
+			config:       vaultrole.CreateConfig{ID: "al9qy", Organizations: []string{"api"}, TTL: "not a duration"},
+			errorMatcher: vaultrole.IsInvalidConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := r.Create(tc.config)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			_, ok := s.Data("", key.WriteRolePath(tc.config.ID, tc.config.Organizations))
+			if ok {
+				t.Fatalf("expected dry run to not write the role")
+			}
+		})
+	}
+}
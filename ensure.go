@@ -0,0 +1,210 @@
+package vaultrole
+
+import (
+	"sync"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/vaultrole/key"
+)
+
+// EnsureMany reconciles the given roles in a single batch. The roles path of
+// each distinct namespace and cluster ID is only listed once. Roles which do
+// not exist yet are created. Roles which do exist are read and only updated in
+// case they differ from the given config, see EnsureResult.Unchanged. Writes are
+// executed concurrently, bounded by Config.EnsureConcurrency. The returned
+// results align with the given configs by index. Configs defining a role
+// already defined by a previous config of the batch are rejected. In case
+// listing the roles of a cluster fails, the configs of that cluster fail while
+// the other clusters are still ensured. In case any config failed, an error is
+// returned in addition to the results, which then carry the per item errors.
+func (r *VaultRole) EnsureMany(configs []EnsureConfig) ([]EnsureResult, error) {
+	results := make([]EnsureResult, len(configs))
+
+	// Merge the referenced profiles first, since they might define the
+	// organizations, which determine the roles. Configs referencing invalid
+	// profiles are not ensured.
+	merged := make([]EnsureConfig, len(configs))
+	{
+		for i, c := range configs {
+			m, err := r.mergeProfile(writeConfig(c))
+			if err != nil {
+				results[i] = EnsureResult{
+					ID:    c.ID,
+					Error: microerror.Mask(err),
+				}
+				continue
+			}
+
+			merged[i] = EnsureConfig(m)
+		}
+	}
+
+	// Reject configs defining the same role as a previous config, since the
+	// existence of roles is only determined once for the whole batch and both
+	// configs would be reported as created.
+	{
+		seen := map[string]int{}
+		for i, c := range merged {
+			if results[i].Error != nil {
+				continue
+			}
+
+			p := lockPath(c.Namespace, c.ID, c.Organizations)
+			if j, ok := seen[p]; ok {
+				results[i] = EnsureResult{
+					ID:    c.ID,
+					Error: microerror.Maskf(invalidConfigError, "config %d defines the same role as config %d", i, j),
+				}
+				continue
+			}
+			seen[p] = i
+		}
+	}
+
+	// Fetch the names of the existing roles once per namespace and cluster ID.
+	// In case listing fails, all configs of the namespace and cluster ID fail
+	// with the listing error.
+	existing := map[string]map[string]bool{}
+	listErrs := map[string]error{}
+	{
+		for i, c := range merged {
+			if results[i].Error != nil {
+				continue
+			}
+			if err, ok := listErrs[c.Namespace+"/"+c.ID]; ok {
+				results[i] = EnsureResult{
+					ID:    c.ID,
+					Error: err,
+				}
+				continue
+			}
+			if _, ok := existing[c.Namespace+"/"+c.ID]; ok {
+				continue
+			}
+
+			names, err := r.listRoleNames(c.Namespace, c.ID)
+			if err != nil {
+				listErrs[c.Namespace+"/"+c.ID] = microerror.Mask(err)
+				results[i] = EnsureResult{
+					ID:    c.ID,
+					Error: microerror.Mask(err),
+				}
+				continue
+			}
+
+			m := map[string]bool{}
+			for _, n := range names {
+				m[n] = true
+			}
+			existing[c.Namespace+"/"+c.ID] = m
+		}
+	}
+
+	// Compute what has to be done for each role and execute the writes.
+	{
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, r.ensureConcurrency)
+
+		for i, c := range merged {
+			if results[i].Error != nil {
+				continue
+			}
+
+			name := key.RoleName(c.ID, c.Organizations)
+
+			results[i] = EnsureResult{
+				ID:            c.ID,
+				RoleName:      name,
+				Organizations: c.Organizations,
+				Created:       !existing[c.Namespace+"/"+c.ID][name],
+			}
+
+			wg.Add(1)
+			go func(i int, c EnsureConfig) {
+				defer wg.Done()
+
+				sem <- struct{}{}
+				defer func() { <-sem }()
+
+				p := lockPath(c.Namespace, c.ID, c.Organizations)
+
+				err := r.locker.Do("ensure", p, c, func() error {
+					if results[i].Created {
+						err := r.write(writeConfig(c))
+						if err != nil {
+							return microerror.Mask(err)
+						}
+
+						r.audit(AuditOperationCreate, writeConfig(c), nil)
+
+						return nil
+					}
+
+					// Existing roles are only written in case they differ
+					// from the desired role, so that resyncing unchanged
+					// roles does not issue any write.
+					_, err := r.Plan(PlanConfig(c))
+					if err != nil {
+						return microerror.Mask(err)
+					}
+
+					current, err := r.readRole(c.Namespace, c.ID, key.ReadRolePath(c.ID, c.Organizations))
+					if err != nil {
+						return microerror.Mask(err)
+					}
+
+					if rolesEqual(current, r.roleFromWriteConfig(writeConfig(c))) {
+						results[i].Unchanged = true
+
+						// The policy is reconciled anyway, since it is not
+						// covered by the comparison.
+						err = r.writePolicy(c.Namespace, c.ID, c.Organizations)
+						if err != nil {
+							return microerror.Mask(err)
+						}
+
+						return nil
+					}
+
+					err = r.write(writeConfig(c))
+					if err != nil {
+						return microerror.Mask(err)
+					}
+
+					r.audit(AuditOperationUpdate, writeConfig(c), &current)
+
+					return nil
+				})
+				if err != nil {
+					results[i].Error = microerror.Mask(err)
+				}
+			}(i, c)
+		}
+
+		wg.Wait()
+	}
+
+	// Aggregate the errors of all failed configs.
+	{
+		var failed int
+		for _, res := range results {
+			if res.Error != nil {
+				failed++
+			}
+		}
+
+		if failed > 0 {
+			return results, microerror.Maskf(executionFailedError, "%d of %d roles failed to be ensured", failed, len(results))
+		}
+	}
+
+	return results, nil
+}
+
+// rolesEqual returns true in case writing the desired role would not change
+// the current role. Extra fields are not managed by VaultRole and therefore
+// ignored.
+func rolesEqual(current Role, desired Role) bool {
+	return managedHash(current) == managedHash(desired) && current.CommonName == desired.CommonName
+}
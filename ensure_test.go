@@ -0,0 +1,235 @@
+package vaultrole_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	vaultclient "github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/vaultrole"
+	"github.com/giantswarm/vaultrole/key"
+	"github.com/giantswarm/vaultrole/middleware"
+	"github.com/giantswarm/vaultrole/vaultroletest"
+)
+
+func Test_VaultRole_EnsureMany(t *testing.T) {
+	s := vaultroletest.NewServer()
+	defer s.Close()
+	s.Mount("", "pki-al9qy")
+
+	client, err := s.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := middleware.NewRecorder()
+
+	// Listing the roles of cluster b9x2z fails.
+	listFailed := errors.New("listing failed")
+	failList := middleware.Func(func(ctx context.Context, req middleware.Request, next middleware.Handler) (*vaultclient.Secret, error) {
+		if req.Operation == middleware.OperationList && strings.Contains(req.Path, "b9x2z") {
+			return nil, listFailed
+		}
+		return next(ctx, req)
+	})
+
+	r, err := s.NewVaultRole(vaultrole.Config{
+		LogicalClient: vaultrole.ChainLogicalClient(vaultrole.NewLogicalClient(client), recorder.Middleware(), failList),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Create(vaultrole.CreateConfig{ID: "al9qy", Organizations: []string{"api"}, TTL: "1h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Create(vaultrole.CreateConfig{ID: "al9qy", Organizations: []string{"etcd"}, TTL: "1h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.Reset()
+
+	configs := []vaultrole.EnsureConfig{
+		{ID: "al9qy", Organizations: []string{"api"}, TTL: "1h"},
+		{ID: "al9qy", Organizations: []string{"etcd"}, TTL: "2h"},
+		{ID: "al9qy", Organizations: []string{"node"}, TTL: "1h"},
+		{ID: "al9qy", Organizations: []string{"invalid"}, TTL: "forever"},
+		{ID: "al9qy", Profile: "missing"},
+		{ID: "al9qy", Organizations: []string{"node"}, TTL: "2h"},
+		{ID: "b9x2z", Organizations: []string{"api"}, TTL: "1h"},
+		{ID: "b9x2z", Organizations: []string{"etcd"}, TTL: "1h"},
+	}
+
+	results, err := r.EnsureMany(configs)
+	if !vaultrole.IsExecutionFailed(err) {
+		t.Fatalf("expected execution failed error got %#v", err)
+	}
+
+	testCases := []struct {
+		name              string
+		expectedCreated   bool
+		expectedUnchanged bool
+		errorMatcher      func(error) bool
+	}{
+		{
+			name:              "case 0: existing role with the desired fields is left unchanged",
+			expectedUnchanged: true,
+		},
+		{
+			name: "case 1: existing role with different fields is updated",
+		},
+		{
+			name:            "case 2: missing role is created",
+			expectedCreated: true,
+		},
+		{
+			name:            "case 3: invalid config fails on its own",
+			expectedCreated: true,
+			errorMatcher:    vaultrole.IsInvalidConfig,
+		},
+		{
+			name:         "case 4: unknown profile fails on its own",
+			errorMatcher: vaultrole.IsInvalidConfig,
+		},
+		{
+			name:         "case 5: role defined by a previous config fails on its own",
+			errorMatcher: vaultrole.IsInvalidConfig,
+		},
+		{
+			name:         "case 6: role of cluster failing to be listed fails on its own",
+			errorMatcher: func(err error) bool { return microerror.Cause(err) == listFailed },
+		},
+		{
+			name:         "case 7: role of cluster failing to be listed fails on its own",
+			errorMatcher: func(err error) bool { return microerror.Cause(err) == listFailed },
+		},
+	}
+
+	if len(results) != len(testCases) {
+		t.Fatalf("expected %d results got %d", len(testCases), len(results))
+	}
+
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := results[i]
+			err := res.Error
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if res.Created != tc.expectedCreated {
+				t.Fatalf("Created == %t, want %t", res.Created, tc.expectedCreated)
+			}
+			if res.Unchanged != tc.expectedUnchanged {
+				t.Fatalf("Unchanged == %t, want %t", res.Unchanged, tc.expectedUnchanged)
+			}
+		})
+	}
+
+	// Only the updated and the created role are written.
+	written := map[string]bool{}
+	for _, rec := range recorder.Recordings() {
+		if rec.Request.Operation == middleware.OperationWrite {
+			written[rec.Request.Path] = true
+		}
+	}
+	expected := map[string]bool{
+		key.WriteRolePath("al9qy", []string{"etcd"}): true,
+		key.WriteRolePath("al9qy", []string{"node"}): true,
+	}
+	if len(written) != len(expected) {
+		t.Fatalf("written == %v, want %v", written, expected)
+	}
+	for p := range expected {
+		if !written[p] {
+			t.Fatalf("written == %v, want %v", written, expected)
+		}
+	}
+
+	// Ensuring the same roles again does not write anything.
+	recorder.Reset()
+	_, err = r.EnsureMany(configs[:3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rec := range recorder.Recordings() {
+		if rec.Request.Operation == middleware.OperationWrite {
+			t.Fatalf("expected no writes got write to %s", rec.Request.Path)
+		}
+	}
+}
+
+func Test_VaultRole_EnsureMany_Concurrency(t *testing.T) {
+	s := vaultroletest.NewServer()
+	defer s.Close()
+	s.Mount("", "pki-al9qy")
+
+	client, err := s.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mutex sync.Mutex
+	var inFlight, maxInFlight int
+	track := middleware.Func(func(ctx context.Context, req middleware.Request, next middleware.Handler) (*vaultclient.Secret, error) {
+		if req.Operation != middleware.OperationWrite {
+			return next(ctx, req)
+		}
+
+		mutex.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mutex.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mutex.Lock()
+		inFlight--
+		mutex.Unlock()
+
+		return next(ctx, req)
+	})
+
+	r, err := s.NewVaultRole(vaultrole.Config{
+		LogicalClient:     vaultrole.ChainLogicalClient(vaultrole.NewLogicalClient(client), track),
+		EnsureConcurrency: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var configs []vaultrole.EnsureConfig
+	for _, o := range []string{"a", "b", "c", "d", "e", "f"} {
+		configs = append(configs, vaultrole.EnsureConfig{ID: "al9qy", Organizations: []string{o}, TTL: "1h"})
+	}
+
+	results, err := r.EnsureMany(configs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, res := range results {
+		if !res.Created {
+			t.Fatalf("expected role %s to be created", res.RoleName)
+		}
+	}
+
+	if maxInFlight > 2 {
+		t.Fatalf("expected at most 2 concurrent writes got %d", maxInFlight)
+	}
+}
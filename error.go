@@ -1,9 +1,11 @@
 package vaultrole
 
 import (
+	"net/http"
 	"strings"
 
 	"github.com/giantswarm/microerror"
+	vaultclient "github.com/hashicorp/vault/api"
 )
 
 var alreadyExistsError = &microerror.Error{
@@ -15,6 +17,33 @@ func IsAlreadyExists(err error) bool {
 	return microerror.Cause(err) == alreadyExistsError
 }
 
+var commonNameMismatchError = &microerror.Error{
+	Kind: "commonNameMismatchError",
+}
+
+// IsCommonNameMismatch asserts commonNameMismatchError.
+func IsCommonNameMismatch(err error) bool {
+	return microerror.Cause(err) == commonNameMismatchError
+}
+
+var conflictError = &microerror.Error{
+	Kind: "conflictError",
+}
+
+// IsConflict asserts conflictError.
+func IsConflict(err error) bool {
+	return microerror.Cause(err) == conflictError
+}
+
+var executionFailedError = &microerror.Error{
+	Kind: "executionFailedError",
+}
+
+// IsExecutionFailed asserts executionFailedError.
+func IsExecutionFailed(err error) bool {
+	return microerror.Cause(err) == executionFailedError
+}
+
 var invalidConfigError = &microerror.Error{
 	Kind: "invalidConfigError",
 }
@@ -42,6 +71,26 @@ func IsNotFound(err error) bool {
 	return microerror.Cause(err) == notFoundError
 }
 
+var notIssuableError = &microerror.Error{
+	Kind: "notIssuableError",
+}
+
+// IsNotIssuable asserts notIssuableError.
+func IsNotIssuable(err error) bool {
+	return microerror.Cause(err) == notIssuableError
+}
+
+// IsPermissionDenied asserts Vault response errors having the status code 403,
+// which Vault responds with e.g. when the token used expired.
+func IsPermissionDenied(err error) bool {
+	responseError, ok := microerror.Cause(err).(*vaultclient.ResponseError)
+	if ok && responseError.StatusCode == http.StatusForbidden {
+		return true
+	}
+
+	return false
+}
+
 // IsNoVaultHandlerDefined asserts a dirty string matching against the error
 // message provided by err. This is necessary due to the poor error handling
 // design of the Vault library we are using.
@@ -0,0 +1,147 @@
+//go:build go1.18
+// +build go1.18
+
+package vaultrole
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// Fuzz_vaultSecretToRole ensures the decoder neither panics nor returns
+// errors other than invalidVaultResponseError for arbitrary responses.
+func Fuzz_vaultSecretToRole(f *testing.F) {
+	f.Add([]byte(`{"allow_bare_domains":true,"allow_subdomains":false,"allowed_domains":"a.com,b.com","organization":"Foo","ttl":"720h"}`))
+	f.Add([]byte(`{"allow_bare_domains":true,"allow_subdomains":false,"allowed_domains":["a.com"],"organization":[],"ttl":3600}`))
+	f.Add([]byte(`{"allow_bare_domains":true,"allow_subdomains":false,"allowed_domains":[],"organization":"","ttl":""}`))
+	f.Add([]byte(`{"allow_bare_domains":false,"allow_subdomains":true,"allowed_domains":[1],"organization":{},"ttl":1e400}`))
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var data map[string]interface{}
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.UseNumber()
+		err := d.Decode(&data)
+		if err != nil {
+			return
+		}
+
+		_, err = vaultSecretToRole(&api.Secret{Data: data})
+		if err != nil && !IsInvalidVaultResponse(err) {
+			t.Fatalf("error == %#v, want invalid Vault response error", err)
+		}
+	})
+}
+
+// Fuzz_roleSchema_roundTrip ensures that decoding the data encoded for a
+// payload yields the role described by the payload, both for the comma
+// separated strings written by VaultRole and for the lists and numbers of
+// seconds Vault 1.x responds with. Names are given as newline separated
+// lists.
+func Fuzz_roleSchema_roundTrip(f *testing.F) {
+	f.Add(true, false, "al9qy.g8s.gigantic.io", "api.internal\nkubernetes", "api\nsystem:masters", int64(2592000))
+	f.Add(false, true, "al9qy.g8s.gigantic.io", "", "", int64(0))
+
+	f.Fuzz(func(t *testing.T, allowBareDomains bool, allowSubdomains bool, commonName string, altNames string, organizations string, ttl int64) {
+		if !validName(commonName) || ttl < 0 || ttl > int64(100*365*24*time.Hour/time.Second) {
+			return
+		}
+		alts, ok := splitNames(altNames)
+		if !ok {
+			return
+		}
+		orgs, ok := splitNames(organizations)
+		if !ok {
+			return
+		}
+
+		p := WritePayload{
+			AllowBareDomains: allowBareDomains,
+			AllowSubdomains:  allowSubdomains,
+			AllowedDomains:   strings.Join(append([]string{commonName}, alts...), ","),
+			Organization:     strings.Join(orgs, ","),
+			TTL:              fmt.Sprintf("%ds", ttl),
+		}
+		expected := Role{
+			AllowBareDomains: allowBareDomains,
+			AllowSubdomains:  allowSubdomains,
+			AltNames:         alts,
+			CommonName:       commonName,
+			Organizations:    orgs,
+			TTL:              time.Duration(ttl) * time.Second,
+		}
+
+		data, err := roleSchema.encode(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Vault 1.x responds with lists and numbers of seconds.
+		lists := map[string]interface{}{}
+		for k, v := range data {
+			lists[k] = v
+		}
+		lists["allowed_domains"] = toInterfaces(append([]string{commonName}, alts...))
+		lists["organization"] = toInterfaces(orgs)
+		lists["ttl"] = json.Number(fmt.Sprintf("%d", ttl))
+
+		for _, d := range []map[string]interface{}{data, lists} {
+			role, err := roleSchema.decode(d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !rolesDeepEqual(role, expected) {
+				t.Fatalf("Role == %#v, want %#v", role, expected)
+			}
+		}
+	})
+}
+
+// rolesDeepEqual compares the given roles treating nil and empty lists as
+// equal.
+func rolesDeepEqual(a Role, b Role) bool {
+	if len(a.AltNames) == 0 && len(b.AltNames) == 0 {
+		a.AltNames, b.AltNames = nil, nil
+	}
+	if len(a.Organizations) == 0 && len(b.Organizations) == 0 {
+		a.Organizations, b.Organizations = nil, nil
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// splitNames splits the given newline separated names. Lists containing names
+// rejected by validateWriteConfig are reported as not ok.
+func splitNames(s string) ([]string, bool) {
+	if s == "" {
+		return nil, true
+	}
+
+	names := strings.Split(s, "\n")
+	for _, n := range names {
+		if !validName(n) {
+			return nil, false
+		}
+	}
+
+	return names, true
+}
+
+func toInterfaces(list []string) []interface{} {
+	l := make([]interface{}, 0, len(list))
+	for _, s := range list {
+		l = append(l, s)
+	}
+
+	return l
+}
+
+func validName(s string) bool {
+	return s != "" && !strings.ContainsAny(s, ",\n")
+}
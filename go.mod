@@ -7,4 +7,5 @@ require (
 	github.com/giantswarm/micrologger v0.3.1
 	github.com/hashicorp/vault/api v1.0.4
 	github.com/hashicorp/vault/sdk v0.1.13
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,65 @@
+package vaultrole
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/giantswarm/microerror"
+)
+
+// RoleHash computes a content hash of the given role which can be used as
+// UpdateConfig.ExpectedHash. The order of alternative names and organizations
+// does not affect the hash. The common name and the extra fields do, so that
+// changes made to them by other processes are detected as conflicts as well.
+func RoleHash(role Role) string {
+	v := struct {
+		CommonName string
+		Extra      map[string]interface{}
+		Managed    string
+	}{
+		CommonName: role.CommonName,
+		Extra:      role.Extra,
+		Managed:    managedHash(role),
+	}
+
+	return hash(v)
+}
+
+// managedHash computes a content hash of the fields of the given role which are
+// managed through the operation configs, ignoring the common name and the extra
+// fields.
+func managedHash(role Role) string {
+	altNames := append([]string(nil), role.AltNames...)
+	sort.Strings(altNames)
+	organizations := append([]string(nil), role.Organizations...)
+	sort.Strings(organizations)
+
+	v := struct {
+		AllowBareDomains bool
+		AllowSubdomains  bool
+		AltNames         []string
+		ID               string
+		Organizations    []string
+		TTL              int64
+	}{
+		AllowBareDomains: role.AllowBareDomains,
+		AllowSubdomains:  role.AllowSubdomains,
+		AltNames:         altNames,
+		ID:               role.ID,
+		Organizations:    organizations,
+		TTL:              int64(role.TTL),
+	}
+
+	return hash(v)
+}
+
+func hash(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(microerror.JSON(err))
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(b))
+}
@@ -0,0 +1,100 @@
+package vaultrole
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_RoleHash(t *testing.T) {
+	base := Role{
+		AllowBareDomains: true,
+		AllowSubdomains:  true,
+		AltNames:         []string{"kubernetes", "kubernetes.default.svc.cluster.local"},
+		ID:               "al9qy",
+		Organizations:    []string{"api", "system:masters"},
+		TTL:              time.Hour,
+	}
+
+	testCases := []struct {
+		name          string
+		role          Role
+		expectedEqual bool
+	}{
+		{
+			name:          "case 0: same role yields same hash",
+			role:          base,
+			expectedEqual: true,
+		},
+		{
+			name: "case 1: order of lists does not affect the hash",
+			role: Role{
+				AllowBareDomains: true,
+				AllowSubdomains:  true,
+				AltNames:         []string{"kubernetes.default.svc.cluster.local", "kubernetes"},
+				ID:               "al9qy",
+				Organizations:    []string{"system:masters", "api"},
+				TTL:              time.Hour,
+			},
+			expectedEqual: true,
+		},
+		{
+			name: "case 2: different TTL yields different hash",
+			role: Role{
+				AllowBareDomains: true,
+				AllowSubdomains:  true,
+				AltNames:         []string{"kubernetes", "kubernetes.default.svc.cluster.local"},
+				ID:               "al9qy",
+				Organizations:    []string{"api", "system:masters"},
+				TTL:              2 * time.Hour,
+			},
+			expectedEqual: false,
+		},
+		{
+			name: "case 3: different alt names yield different hash",
+			role: Role{
+				AllowBareDomains: true,
+				AllowSubdomains:  true,
+				AltNames:         []string{"kubernetes"},
+				ID:               "al9qy",
+				Organizations:    []string{"api", "system:masters"},
+				TTL:              time.Hour,
+			},
+			expectedEqual: false,
+		},
+		{
+			name: "case 4: different common name yields different hash",
+			role: Role{
+				AllowBareDomains: true,
+				AllowSubdomains:  true,
+				AltNames:         []string{"kubernetes", "kubernetes.default.svc.cluster.local"},
+				CommonName:       "al9qy.g8s.gigantic.io",
+				ID:               "al9qy",
+				Organizations:    []string{"api", "system:masters"},
+				TTL:              time.Hour,
+			},
+			expectedEqual: false,
+		},
+		{
+			name: "case 5: different extra fields yield different hash",
+			role: Role{
+				AllowBareDomains: true,
+				AllowSubdomains:  true,
+				AltNames:         []string{"kubernetes", "kubernetes.default.svc.cluster.local"},
+				ID:               "al9qy",
+				Organizations:    []string{"api", "system:masters"},
+				TTL:              time.Hour,
+				Extra:            map[string]interface{}{"key_type": "ec"},
+			},
+			expectedEqual: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			equal := RoleHash(tc.role) == RoleHash(base)
+			if equal != tc.expectedEqual {
+				t.Fatalf("equal == %v, want %v", equal, tc.expectedEqual)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package vaultrole
+
+import (
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/vaultrole/key"
+)
+
+// Certificate is a certificate issued by Issue. All certificates and keys are
+// PEM encoded.
+type Certificate struct {
+	Certificate    string
+	Expiration     time.Time
+	IssuingCA      string
+	PrivateKey     string
+	PrivateKeyType string
+	SerialNumber   string
+}
+
+// Issue issues a certificate using the role of the given cluster ID and
+// organizations. Issuing certificates does not change any role, so Issue is
+// executed in dry run mode as well.
+func (r *VaultRole) Issue(config IssueConfig) (Certificate, error) {
+	if config.ID == "" {
+		return Certificate{}, microerror.Maskf(invalidConfigError, "config.ID must not be empty")
+	}
+	if config.CommonName == "" {
+		return Certificate{}, microerror.Maskf(invalidConfigError, "config.CommonName must not be empty")
+	}
+
+	p := key.IssuePath(config.ID, append([]string(nil), config.Organizations...))
+	v := map[string]interface{}{
+		"common_name": config.CommonName,
+	}
+	if len(config.AltNames) > 0 {
+		v["alt_names"] = strings.Join(config.AltNames, ",")
+	}
+	if len(config.IPSANs) > 0 {
+		v["ip_sans"] = strings.Join(config.IPSANs, ",")
+	}
+	if config.TTL != "" {
+		v["ttl"] = config.TTL
+	}
+
+	var secret *api.Secret
+	err := r.withReauth(func() error {
+		var err error
+		secret, err = r.logicalClient.Write(r.ctx, config.Namespace, p, v)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return Certificate{}, microerror.Mask(err)
+	}
+
+	if secret == nil {
+		return Certificate{}, microerror.Maskf(invalidVaultResponseError, "no certificate issued at path '%s'", p)
+	}
+
+	var c Certificate
+	for k, f := range map[string]*string{
+		"certificate":      &c.Certificate,
+		"issuing_ca":       &c.IssuingCA,
+		"private_key":      &c.PrivateKey,
+		"private_key_type": &c.PrivateKeyType,
+		"serial_number":    &c.SerialNumber,
+	} {
+		s, ok := secret.Data[k].(string)
+		if !ok {
+			return Certificate{}, microerror.Maskf(invalidVaultResponseError, "Vault secret.Data[%q] type is %T, expected string", k, secret.Data[k])
+		}
+		*f = s
+	}
+
+	expiration, err := parseUnixTime("expiration", secret.Data["expiration"])
+	if err != nil {
+		return Certificate{}, microerror.Mask(err)
+	}
+	c.Expiration = expiration
+
+	return c, nil
+}
@@ -0,0 +1,74 @@
+package vaultrole_test
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/vaultrole"
+	"github.com/giantswarm/vaultrole/vaultroletest"
+)
+
+func Test_VaultRole_Issue(t *testing.T) {
+	s := vaultroletest.NewServer()
+	defer s.Close()
+	s.Mount("", "pki-al9qy")
+
+	r, err := s.NewVaultRole(vaultrole.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := vaultrole.IssueConfig{
+		AltNames:      []string{"kubernetes"},
+		CommonName:    "api.al9qy.g8s.gigantic.io",
+		ID:            "al9qy",
+		Organizations: []string{"api"},
+		TTL:           "1h",
+	}
+
+	_, err = r.Issue(c)
+	if err == nil {
+		t.Fatal("expected issuing against a missing role to fail")
+	}
+
+	err = r.Create(vaultrole.CreateConfig{ID: "al9qy", Organizations: []string{"api"}, AltNames: []string{"kubernetes"}, TTL: "24h", AllowSubdomains: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := r.Issue(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := pem.Decode([]byte(cert.Certificate))
+	if b == nil {
+		t.Fatal("expected PEM encoded certificate")
+	}
+	leaf, err := x509.ParseCertificate(b.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if leaf.Subject.CommonName != c.CommonName {
+		t.Fatalf("common name == %q, want %q", leaf.Subject.CommonName, c.CommonName)
+	}
+	if !reflect.DeepEqual(leaf.Subject.Organization, []string{"api"}) {
+		t.Fatalf("organizations == %#v, want %#v", leaf.Subject.Organization, []string{"api"})
+	}
+	if !reflect.DeepEqual(leaf.DNSNames, []string{c.CommonName, "kubernetes"}) {
+		t.Fatalf("DNS names == %#v, want %#v", leaf.DNSNames, []string{c.CommonName, "kubernetes"})
+	}
+	if d := leaf.NotAfter.Sub(leaf.NotBefore); d != time.Hour {
+		t.Fatalf("lifetime == %s, want %s", d, time.Hour)
+	}
+	if !cert.Expiration.Equal(leaf.NotAfter) {
+		t.Fatalf("expiration == %s, want %s", cert.Expiration, leaf.NotAfter)
+	}
+	if cert.SerialNumber == "" || cert.PrivateKey == "" || cert.IssuingCA == "" {
+		t.Fatalf("expected serial number, private key and issuing CA, got %#v", cert)
+	}
+}
@@ -13,19 +13,66 @@ import (
 // first item is the common name. This has to be considered in ToAltNames when
 // reverse computing the list of allowed domains.
 func AllowedDomains(ID, commonNameFormat string, altNames []string) string {
-	commonName := fmt.Sprintf(commonNameFormat, ID)
+	return JoinAllowedDomains(fmt.Sprintf(commonNameFormat, ID), altNames)
+}
+
+// CertPath returns the path the certificate of the given serial number is read
+// from.
+func CertPath(ID string, serialNumber string) string {
+	return fmt.Sprintf("pki-%s/cert/%s", ID, serialNumber)
+}
+
+// IssuePath returns the path certificates are issued at using the role of the
+// given cluster ID and organizations.
+func IssuePath(ID string, organizations []string) string {
+	return fmt.Sprintf("pki-%s/issue/%s", ID, RoleName(ID, organizations))
+}
+
+// JoinAllowedDomains computes the list of allowed domains like AllowedDomains
+// for an already computed common name.
+func JoinAllowedDomains(commonName string, altNames []string) string {
 	domains := append([]string{commonName}, altNames...)
 	return strings.Join(domains, ",")
 }
 
+// ListCertsPath returns the path listing the serial numbers of the
+// certificates issued for the given cluster ID.
+func ListCertsPath(ID string) string {
+	return fmt.Sprintf("pki-%s/certs", ID)
+}
+
 func ListRolesPath(ID string) string {
 	return fmt.Sprintf("pki-%s/roles/", ID)
 }
 
+// NamedRolePath returns the path of the role with the given name, as e.g.
+// returned when listing the roles of a cluster.
+func NamedRolePath(ID string, roleName string) string {
+	return fmt.Sprintf("pki-%s/roles/%s", ID, roleName)
+}
+
+// PolicyName returns the name of the ACL policy granting issuing certificates
+// using the role of the given cluster ID and organizations.
+func PolicyName(ID string, organizations []string) string {
+	return fmt.Sprintf("pki-%s-%s", ID, RoleName(ID, organizations))
+}
+
+// PolicyPath returns the path the ACL policy with the given name is written
+// to.
+func PolicyPath(name string) string {
+	return fmt.Sprintf("sys/policies/acl/%s", name)
+}
+
 func ReadRolePath(ID string, organizations []string) string {
 	return fmt.Sprintf("pki-%s/roles/%s", ID, RoleName(ID, organizations))
 }
 
+// RevokePath returns the path certificates issued for the given cluster ID are
+// revoked at.
+func RevokePath(ID string) string {
+	return fmt.Sprintf("pki-%s/revoke", ID)
+}
+
 func RoleName(ID string, organizations []string) string {
 	if len(organizations) == 0 {
 		// If organizations isn't set, use the role that was created when the PKI
@@ -38,6 +85,12 @@ func RoleName(ID string, organizations []string) string {
 	return fmt.Sprintf("role-org-%s", computeOrgHash(organizations))
 }
 
+// SignPath returns the path certificate signing requests are signed at using
+// the role of the given cluster ID and organizations.
+func SignPath(ID string, organizations []string) string {
+	return fmt.Sprintf("pki-%s/sign/%s", ID, RoleName(ID, organizations))
+}
+
 // ToAltNames takes a string as provided by AllowedDomains and returns the list
 // of alternative names as taken by AllowedDomains. Note this implies dropping
 // the first item of the parsed list.
@@ -46,6 +46,141 @@ func Test_AllowedDomains(t *testing.T) {
 	}
 }
 
+func Test_JoinAllowedDomains(t *testing.T) {
+	testCases := []struct {
+		CommonName     string
+		AltNames       []string
+		ExpectedResult string
+	}{
+		{
+			CommonName: "api.al9qy.example.com",
+			AltNames: []string{
+				"kubernetes",
+			},
+			ExpectedResult: "api.al9qy.example.com,kubernetes",
+		},
+
+		{
+			CommonName:     "api.al9qy.example.com",
+			AltNames:       nil,
+			ExpectedResult: "api.al9qy.example.com",
+		},
+	}
+
+	for i, tc := range testCases {
+		result := JoinAllowedDomains(tc.CommonName, tc.AltNames)
+
+		if result != tc.ExpectedResult {
+			t.Fatalf("case %d expected %#v got %#v", i, tc.ExpectedResult, result)
+		}
+	}
+}
+
+func Test_Paths(t *testing.T) {
+	orgs := func() []string {
+		return []string{"system:masters", "api"}
+	}
+
+	testCases := []struct {
+		Result         string
+		ExpectedResult string
+	}{
+		// Case 0: CertPath.
+		{
+			Result:         CertPath("al9qy", "5b-c1-52-76"),
+			ExpectedResult: "pki-al9qy/cert/5b-c1-52-76",
+		},
+		// Case 1: IssuePath without organizations uses the base role.
+		{
+			Result:         IssuePath("al9qy", nil),
+			ExpectedResult: "pki-al9qy/issue/role-al9qy",
+		},
+		// Case 2: IssuePath with organizations.
+		{
+			Result:         IssuePath("al9qy", orgs()),
+			ExpectedResult: "pki-al9qy/issue/role-org-7395c031992f478e2e0e8d3198272008d407e1bc209c0cd52048fdebdd4ac1e0afd1d904044d9a9a2b0fe515579a56a4daf2aea7092518218ef985371890109f",
+		},
+		// Case 3: ListCertsPath.
+		{
+			Result:         ListCertsPath("al9qy"),
+			ExpectedResult: "pki-al9qy/certs",
+		},
+		// Case 4: ListRolesPath.
+		{
+			Result:         ListRolesPath("al9qy"),
+			ExpectedResult: "pki-al9qy/roles/",
+		},
+		// Case 5: NamedRolePath.
+		{
+			Result:         NamedRolePath("al9qy", "custom"),
+			ExpectedResult: "pki-al9qy/roles/custom",
+		},
+		// Case 6: PolicyPath.
+		{
+			Result:         PolicyPath("pki-al9qy-role-al9qy"),
+			ExpectedResult: "sys/policies/acl/pki-al9qy-role-al9qy",
+		},
+		// Case 7: ReadRolePath.
+		{
+			Result:         ReadRolePath("al9qy", orgs()),
+			ExpectedResult: "pki-al9qy/roles/role-org-7395c031992f478e2e0e8d3198272008d407e1bc209c0cd52048fdebdd4ac1e0afd1d904044d9a9a2b0fe515579a56a4daf2aea7092518218ef985371890109f",
+		},
+		// Case 8: RevokePath.
+		{
+			Result:         RevokePath("al9qy"),
+			ExpectedResult: "pki-al9qy/revoke",
+		},
+		// Case 9: SignPath.
+		{
+			Result:         SignPath("al9qy", orgs()),
+			ExpectedResult: "pki-al9qy/sign/role-org-7395c031992f478e2e0e8d3198272008d407e1bc209c0cd52048fdebdd4ac1e0afd1d904044d9a9a2b0fe515579a56a4daf2aea7092518218ef985371890109f",
+		},
+		// Case 10: WriteRolePath.
+		{
+			Result:         WriteRolePath("al9qy", orgs()),
+			ExpectedResult: "pki-al9qy/roles/role-org-7395c031992f478e2e0e8d3198272008d407e1bc209c0cd52048fdebdd4ac1e0afd1d904044d9a9a2b0fe515579a56a4daf2aea7092518218ef985371890109f",
+		},
+	}
+
+	for i, tc := range testCases {
+		if tc.Result != tc.ExpectedResult {
+			t.Fatalf("case %d expected %#v got %#v", i, tc.ExpectedResult, tc.Result)
+		}
+	}
+}
+
+func Test_PolicyName(t *testing.T) {
+	testCases := []struct {
+		ID             string
+		Organizations  []string
+		ExpectedResult string
+	}{
+		// Case 0: Without orgs, the policy is named after the base role.
+		{
+			ID:             "al9qy",
+			Organizations:  nil,
+			ExpectedResult: "pki-al9qy-role-al9qy",
+		},
+		// Case 1: The order of the orgs does not affect the name.
+		{
+			ID: "al9qy",
+			Organizations: []string{
+				"system:masters",
+				"api",
+			},
+			ExpectedResult: "pki-al9qy-role-org-7395c031992f478e2e0e8d3198272008d407e1bc209c0cd52048fdebdd4ac1e0afd1d904044d9a9a2b0fe515579a56a4daf2aea7092518218ef985371890109f",
+		},
+	}
+
+	for i, tc := range testCases {
+		result := PolicyName(tc.ID, tc.Organizations)
+
+		if result != tc.ExpectedResult {
+			t.Fatalf("case %d expected %#v got %#v", i, tc.ExpectedResult, result)
+		}
+	}
+}
+
 func Test_RoleName(t *testing.T) {
 	testCases := []struct {
 		ID             string
@@ -0,0 +1,96 @@
+package vaultrole
+
+import (
+	"fmt"
+	"sync"
+)
+
+// pathLocker serialises operations per Vault role path and deduplicates
+// identical operations being executed concurrently for the same role path.
+type pathLocker struct {
+	mutex sync.Mutex
+	calls map[string]*call
+	locks map[string]*pathLock
+
+	// joined is optionally called whenever a call joins the call in flight.
+	// It is used by tests to synchronise with joining calls.
+	joined func()
+}
+
+type call struct {
+	done chan struct{}
+	err  error
+}
+
+type pathLock struct {
+	mutex sync.Mutex
+	refs  int
+}
+
+func newPathLocker() *pathLocker {
+	l := &pathLocker{
+		calls: map[string]*call{},
+		locks: map[string]*pathLock{},
+	}
+
+	return l
+}
+
+// Do executes fn while holding the lock of the given path. Concurrent calls
+// for the same path and with an equal config do not execute fn again but wait
+// for the call in flight and share its result.
+func (l *pathLocker) Do(op string, path string, config interface{}, fn func() error) error {
+	k := fmt.Sprintf("%s/%s/%#v", op, path, config)
+
+	l.mutex.Lock()
+	if c, ok := l.calls[k]; ok {
+		l.mutex.Unlock()
+		if l.joined != nil {
+			l.joined()
+		}
+		<-c.done
+		return c.err
+	}
+	c := &call{
+		done: make(chan struct{}),
+	}
+	l.calls[k] = c
+	l.mutex.Unlock()
+
+	unlock := l.lock(path)
+	c.err = fn()
+	unlock()
+
+	l.mutex.Lock()
+	delete(l.calls, k)
+	l.mutex.Unlock()
+	close(c.done)
+
+	return c.err
+}
+
+// lock acquires the lock of the given path and returns the function releasing
+// it again. Locks are dropped as soon as nobody is holding or waiting for them.
+func (l *pathLocker) lock(path string) func() {
+	l.mutex.Lock()
+	p, ok := l.locks[path]
+	if !ok {
+		p = &pathLock{}
+		l.locks[path] = p
+	}
+	p.refs++
+	l.mutex.Unlock()
+
+	p.mutex.Lock()
+
+	return func() {
+		p.mutex.Unlock()
+
+		l.mutex.Lock()
+		p.refs--
+		if p.refs == 0 {
+			delete(l.locks, path)
+		}
+		l.mutex.Unlock()
+	}
+}
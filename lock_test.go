@@ -0,0 +1,90 @@
+package vaultrole
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_pathLocker_Do_Deduplicate(t *testing.T) {
+	l := newPathLocker()
+
+	var joined sync.WaitGroup
+	l.joined = joined.Done
+
+	var mutex sync.Mutex
+	var executed int
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	do := func(wg *sync.WaitGroup) {
+		defer wg.Done()
+		_ = l.Do("create", "pki-al9qy/roles/role-al9qy", CreateConfig{ID: "al9qy"}, func() error {
+			mutex.Lock()
+			executed++
+			mutex.Unlock()
+			close(started)
+			<-release
+			return nil
+		})
+	}
+
+	var wg sync.WaitGroup
+
+	// Start the call in flight first, so that all other calls join it.
+	wg.Add(1)
+	go do(&wg)
+	<-started
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		joined.Add(1)
+		go do(&wg)
+	}
+
+	joined.Wait()
+	close(release)
+	wg.Wait()
+
+	if executed != 1 {
+		t.Fatalf("expected 1 execution got %d", executed)
+	}
+}
+
+func Test_pathLocker_Do_Serialise(t *testing.T) {
+	l := newPathLocker()
+
+	var mutex sync.Mutex
+	var running, maxRunning int
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_ = l.Do("update", "pki-al9qy/roles/role-al9qy", UpdateConfig{ID: "al9qy", TTL: time.Duration(i).String()}, func() error {
+				mutex.Lock()
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				mutex.Unlock()
+
+				time.Sleep(10 * time.Millisecond)
+
+				mutex.Lock()
+				running--
+				mutex.Unlock()
+				return nil
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	if maxRunning != 1 {
+		t.Fatalf("expected at most 1 concurrent execution got %d", maxRunning)
+	}
+	if len(l.locks) != 0 {
+		t.Fatalf("expected locks to be released got %d", len(l.locks))
+	}
+}
@@ -0,0 +1,143 @@
+package vaultrole
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/giantswarm/microerror"
+	vaultclient "github.com/hashicorp/vault/api"
+)
+
+// LogicalClient issues requests against the logical API of Vault within the
+// given Vault Enterprise namespace. In case the namespace is empty, the
+// default namespace of the client applies. Reading, listing or deleting a
+// path which does not exist returns a nil secret and no error, like
+// vaultclient.Logical does. VaultRole issues all requests using
+// Config.Context. See NewLogicalClient for the implementation using the Vault
+// API client and ChainLogicalClient for adding middlewares.
+type LogicalClient interface {
+	Delete(ctx context.Context, namespace string, path string) (*vaultclient.Secret, error)
+	List(ctx context.Context, namespace string, path string) (*vaultclient.Secret, error)
+	Read(ctx context.Context, namespace string, path string) (*vaultclient.Secret, error)
+	Write(ctx context.Context, namespace string, path string, data map[string]interface{}) (*vaultclient.Secret, error)
+}
+
+// Middleware wraps a LogicalClient, e.g. in order to log, measure, retry or
+// record requests. See package middleware for implementations.
+type Middleware func(next LogicalClient) LogicalClient
+
+// ChainLogicalClient wraps the given client with the given middlewares. The
+// first middleware is the outermost one, so it sees every request first.
+func ChainLogicalClient(client LogicalClient, middlewares ...Middleware) LogicalClient {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		client = middlewares[i](client)
+	}
+
+	return client
+}
+
+// NewLogicalClient returns a LogicalClient issuing requests using the given
+// Vault API client. The token and headers of the client are looked up on
+// every request, so that re-authenticating the client applies to subsequent
+// requests.
+func NewLogicalClient(client *vaultclient.Client) LogicalClient {
+	return &vaultLogicalClient{
+		client: client,
+	}
+}
+
+type vaultLogicalClient struct {
+	client *vaultclient.Client
+}
+
+func (c *vaultLogicalClient) Delete(ctx context.Context, namespace string, path string) (*vaultclient.Secret, error) {
+	return c.do(ctx, namespace, http.MethodDelete, path, nil)
+}
+
+func (c *vaultLogicalClient) List(ctx context.Context, namespace string, path string) (*vaultclient.Secret, error) {
+	return c.do(ctx, namespace, "LIST", path, nil)
+}
+
+func (c *vaultLogicalClient) Read(ctx context.Context, namespace string, path string) (*vaultclient.Secret, error) {
+	return c.do(ctx, namespace, http.MethodGet, path, nil)
+}
+
+func (c *vaultLogicalClient) Write(ctx context.Context, namespace string, path string, data map[string]interface{}) (*vaultclient.Secret, error) {
+	return c.do(ctx, namespace, http.MethodPut, path, data)
+}
+
+// do issues the given request the way vaultclient.Logical does, while
+// honouring the given context and namespace.
+func (c *vaultLogicalClient) do(ctx context.Context, namespace string, method string, path string, data map[string]interface{}) (*vaultclient.Secret, error) {
+	client, err := c.clientFor(namespace)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	r := client.NewRequest(method, "/v1/"+path)
+	if method == "LIST" {
+		// Listing is issued as GET for broader compatibility, like
+		// vaultclient.Logical does.
+		r.Method = http.MethodGet
+		r.Params.Set("list", "true")
+	}
+	if data != nil {
+		err := r.SetJSONBody(data)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	resp, err := client.RawRequestWithContext(ctx, r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		secret, parseErr := vaultclient.ParseSecret(resp.Body)
+		if parseErr != nil {
+			return nil, err
+		}
+		read := method == http.MethodGet || method == "LIST"
+		if secret != nil && (len(secret.Warnings) > 0 || len(secret.Data) > 0) {
+			if read {
+				return secret, nil
+			}
+			return secret, err
+		}
+		// Paths which do not exist are not an error when reading or listing.
+		if read {
+			return nil, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := vaultclient.ParseSecret(resp.Body)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return secret, nil
+}
+
+// clientFor returns the Vault client issuing requests against the given Vault
+// Enterprise namespace. In case the namespace is empty, the configured client
+// is returned.
+func (c *vaultLogicalClient) clientFor(namespace string) (*vaultclient.Client, error) {
+	if namespace == "" {
+		return c.client, nil
+	}
+
+	// The configured Vault client is shared, so a clone is used in order to
+	// not modify the namespace of concurrent requests.
+	clone, err := c.client.Clone()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	clone.SetHeaders(c.client.Headers())
+	clone.SetToken(c.client.Token())
+	clone.SetNamespace(namespace)
+
+	return clone, nil
+}
@@ -0,0 +1,241 @@
+package vaultrole_test
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	vaultclient "github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/vaultrole"
+	"github.com/giantswarm/vaultrole/middleware"
+	"github.com/giantswarm/vaultrole/vaultroletest"
+)
+
+func Test_ChainLogicalClient(t *testing.T) {
+	var calls []string
+
+	named := func(name string) vaultrole.Middleware {
+		return func(next vaultrole.LogicalClient) vaultrole.LogicalClient {
+			return &tracingClient{name: name, calls: &calls, next: next}
+		}
+	}
+
+	c := vaultrole.ChainLogicalClient(&tracingClient{name: "client", calls: &calls}, named("a"), named("b"))
+
+	_, err := c.Read(context.Background(), "", "pki-al9qy/roles/role-al9qy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"a", "b", "client"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Fatalf("calls == %#v, want %#v", calls, expected)
+	}
+}
+
+func Test_NewLogicalClient(t *testing.T) {
+	s := vaultroletest.NewServer()
+	defer s.Close()
+	s.Mount("team-a", "pki-al9qy")
+
+	client, err := s.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := vaultrole.NewLogicalClient(client)
+	ctx := context.Background()
+
+	_, err = c.Write(ctx, "team-a", "pki-al9qy/roles/role-al9qy", map[string]interface{}{"ttl": "1h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	{
+		secret, err := c.Read(ctx, "team-a", "pki-al9qy/roles/role-al9qy")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret == nil || secret.Data["ttl"] == nil {
+			t.Fatalf("expected role to be read, got %#v", secret)
+		}
+	}
+
+	{
+		secret, err := c.List(ctx, "team-a", "pki-al9qy/roles")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret == nil || !reflect.DeepEqual(secret.Data["keys"], []interface{}{"role-al9qy"}) {
+			t.Fatalf("expected role to be listed, got %#v", secret)
+		}
+	}
+
+	// Paths which do not exist, including paths of other namespaces, are
+	// read as nil secrets.
+	for _, ns := range []string{"team-a", ""} {
+		secret, err := c.Read(ctx, ns, "pki-al9qy/roles/role-5xchu")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret != nil {
+			t.Fatalf("expected nil secret, got %#v", secret)
+		}
+	}
+
+	_, err = c.Delete(ctx, "team-a", "pki-al9qy/roles/role-al9qy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Data("team-a", "pki-al9qy/roles/role-al9qy"); ok {
+		t.Fatal("expected role to be deleted")
+	}
+
+	// The namespace of the shared client must not be modified.
+	if ns := client.Headers().Get("X-Vault-Namespace"); ns != "" {
+		t.Fatalf("client namespace == %q, want empty", ns)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = c.Read(canceled, "team-a", "pki-al9qy/roles/role-al9qy")
+	if err == nil {
+		t.Fatal("expected request using a canceled context to fail")
+	}
+}
+
+func Test_New_LogicalClient(t *testing.T) {
+	client, err := vaultclient.NewClient(vaultclient.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name         string
+		config       vaultrole.Config
+		errorMatcher func(error) bool
+	}{
+		{
+			name:   "case 0: logical client",
+			config: vaultrole.Config{Logger: microloggertest.New(), LogicalClient: vaultrole.NewLogicalClient(client), CommonNameFormat: "%s.g8s.gigantic.io"},
+		},
+		{
+			name:         "case 1: neither Vault client nor logical client",
+			config:       vaultrole.Config{Logger: microloggertest.New(), CommonNameFormat: "%s.g8s.gigantic.io"},
+			errorMatcher: vaultrole.IsInvalidConfig,
+		},
+		{
+			name:         "case 2: both Vault client and logical client",
+			config:       vaultrole.Config{Logger: microloggertest.New(), VaultClient: client, LogicalClient: vaultrole.NewLogicalClient(client), CommonNameFormat: "%s.g8s.gigantic.io"},
+			errorMatcher: vaultrole.IsInvalidConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := vaultrole.New(tc.config)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+		})
+	}
+}
+
+// Test_VaultRole_Context ensures that requests are issued using
+// Config.Context, so that cancelling it stops the retries of
+// middleware.NewRetry.
+func Test_VaultRole_Context(t *testing.T) {
+	s := vaultroletest.NewServer()
+	defer s.Close()
+	s.Mount("", "pki-al9qy")
+
+	client, err := s.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	retry, err := middleware.NewRetry(middleware.RetryConfig{Logger: microloggertest.New(), Backoff: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Vault is unavailable and the context gets cancelled while the first
+	// request is in flight.
+	var calls int
+	unavailable := middleware.Func(func(ctx context.Context, req middleware.Request, next middleware.Handler) (*vaultclient.Secret, error) {
+		calls++
+		cancel()
+		return nil, &vaultclient.ResponseError{StatusCode: http.StatusServiceUnavailable}
+	})
+
+	r, err := s.NewVaultRole(vaultrole.Config{
+		Context:       ctx,
+		LogicalClient: vaultrole.ChainLogicalClient(vaultrole.NewLogicalClient(client), retry, unavailable),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = r.Exists(vaultrole.ExistsConfig{ID: "al9qy"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 1 {
+		t.Fatalf("calls == %d, want %d", calls, 1)
+	}
+}
+
+// tracingClient records its name whenever it is called before calling the
+// next client, if any.
+type tracingClient struct {
+	name  string
+	calls *[]string
+	next  vaultrole.LogicalClient
+}
+
+func (c *tracingClient) Delete(ctx context.Context, namespace string, path string) (*vaultclient.Secret, error) {
+	*c.calls = append(*c.calls, c.name)
+	if c.next == nil {
+		return nil, nil
+	}
+	return c.next.Delete(ctx, namespace, path)
+}
+
+func (c *tracingClient) List(ctx context.Context, namespace string, path string) (*vaultclient.Secret, error) {
+	*c.calls = append(*c.calls, c.name)
+	if c.next == nil {
+		return nil, nil
+	}
+	return c.next.List(ctx, namespace, path)
+}
+
+func (c *tracingClient) Read(ctx context.Context, namespace string, path string) (*vaultclient.Secret, error) {
+	*c.calls = append(*c.calls, c.name)
+	if c.next == nil {
+		return nil, nil
+	}
+	return c.next.Read(ctx, namespace, path)
+}
+
+func (c *tracingClient) Write(ctx context.Context, namespace string, path string, data map[string]interface{}) (*vaultclient.Secret, error) {
+	*c.calls = append(*c.calls, c.name)
+	if c.next == nil {
+		return nil, nil
+	}
+	return c.next.Write(ctx, namespace, path, data)
+}
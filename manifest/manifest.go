@@ -0,0 +1,258 @@
+// Package manifest loads declarative role definitions from YAML or JSON
+// documents and reconciles them against Vault. A manifest looks as follows.
+//
+//	roles:
+//	- clusterID: al9qy
+//	  organizations:
+//	  - api
+//	  - system:masters
+//	  altNames:
+//	  - kubernetes
+//	  ttl: 8640h
+//	  allowBareDomains: true
+//	  allowSubdomains: true
+//
+// Roles may additionally define a namespace, a profile and a common name
+// format, see vaultrole.CreateConfig. A single input may contain multiple YAML
+// documents.
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/hashicorp/vault/sdk/helper/parseutil"
+	"gopkg.in/yaml.v3"
+
+	"github.com/giantswarm/vaultrole"
+)
+
+// Load parses the given manifest and returns the roles it defines. In case the
+// manifest is invalid, an invalidManifestError listing every problem found
+// along with its line and column is returned.
+func Load(data []byte) ([]vaultrole.CreateConfig, error) {
+	var entries []entry
+	var problems []string
+
+	d := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var n yaml.Node
+		err := d.Decode(&n)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, microerror.Maskf(invalidManifestError, "%s", err.Error())
+		}
+
+		e, p := parseDocument(&n)
+		entries = append(entries, e...)
+		problems = append(problems, p...)
+	}
+
+	problems = append(problems, findDuplicates(entries)...)
+
+	if len(problems) > 0 {
+		return nil, microerror.Maskf(invalidManifestError, "%s", strings.Join(problems, "\n"))
+	}
+
+	var configs []vaultrole.CreateConfig
+	for _, e := range entries {
+		configs = append(configs, e.config)
+	}
+
+	return configs, nil
+}
+
+// Reconcile ensures that every role of the given manifest exists in Vault with
+// the defined fields, see vaultrole.EnsureInterface. Roles not defined by the
+// manifest are left untouched.
+func Reconcile(vaultRole vaultrole.EnsureInterface, data []byte) ([]vaultrole.EnsureResult, error) {
+	configs, err := Load(data)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var ensureConfigs []vaultrole.EnsureConfig
+	for _, c := range configs {
+		ensureConfigs = append(ensureConfigs, vaultrole.EnsureConfig(c))
+	}
+
+	results, err := vaultRole.EnsureMany(ensureConfigs)
+	if err != nil {
+		return results, microerror.Mask(err)
+	}
+
+	return results, nil
+}
+
+// entry is a parsed role along with the node defining it.
+type entry struct {
+	config vaultrole.CreateConfig
+	node   *yaml.Node
+}
+
+func parseDocument(n *yaml.Node) ([]entry, []string) {
+	if n.Kind == yaml.DocumentNode {
+		if len(n.Content) == 0 {
+			return nil, nil
+		}
+		n = n.Content[0]
+	}
+
+	if n.Kind != yaml.MappingNode {
+		return nil, []string{problem(n, "manifest must be a mapping")}
+	}
+
+	var entries []entry
+	var problems []string
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+
+		switch k.Value {
+		case "roles":
+			if v.Kind != yaml.SequenceNode {
+				problems = append(problems, problem(v, "roles must be a list"))
+				continue
+			}
+			for _, item := range v.Content {
+				c, p := parseRole(item)
+				if len(p) > 0 {
+					problems = append(problems, p...)
+					continue
+				}
+				entries = append(entries, entry{config: c, node: item})
+			}
+		default:
+			problems = append(problems, problem(k, fmt.Sprintf("unknown field %q", k.Value)))
+		}
+	}
+
+	return entries, problems
+}
+
+func parseRole(n *yaml.Node) (vaultrole.CreateConfig, []string) {
+	if n.Kind != yaml.MappingNode {
+		return vaultrole.CreateConfig{}, []string{problem(n, "role must be a mapping")}
+	}
+
+	var c vaultrole.CreateConfig
+	var problems []string
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+
+		var err string
+		switch k.Value {
+		case "allowBareDomains":
+			c.AllowBareDomains, err = parseBool(v)
+		case "allowSubdomains":
+			c.AllowSubdomains, err = parseBool(v)
+		case "altNames":
+			c.AltNames, err = parseStrings(v)
+		case "clusterID":
+			c.ID, err = parseString(v)
+		case "commonNameFormat":
+			c.CommonNameFormat, err = parseString(v)
+		case "namespace":
+			c.Namespace, err = parseString(v)
+		case "organizations":
+			c.Organizations, err = parseStrings(v)
+		case "profile":
+			c.Profile, err = parseString(v)
+		case "ttl":
+			c.TTL, err = parseString(v)
+			if err == "" {
+				_, perr := parseutil.ParseDurationSecond(c.TTL)
+				if perr != nil {
+					err = fmt.Sprintf("ttl must be a duration like 8640h, got %q", c.TTL)
+				}
+			}
+		default:
+			err = fmt.Sprintf("unknown field %q", k.Value)
+			v = k
+		}
+
+		if err != "" {
+			problems = append(problems, problem(v, err))
+		}
+	}
+
+	if c.ID == "" {
+		problems = append(problems, problem(n, "clusterID must not be empty"))
+	}
+
+	if len(problems) > 0 {
+		return vaultrole.CreateConfig{}, problems
+	}
+
+	return c, nil
+}
+
+// findDuplicates reports roles defined more than once. Roles are identical in
+// case they share the namespace, cluster ID and set of organizations.
+func findDuplicates(entries []entry) []string {
+	var problems []string
+
+	seen := map[string]*yaml.Node{}
+	for _, e := range entries {
+		orgs := append([]string(nil), e.config.Organizations...)
+		sort.Strings(orgs)
+
+		k := fmt.Sprintf("%s/%s/%s", e.config.Namespace, e.config.ID, strings.Join(orgs, ","))
+		if n, ok := seen[k]; ok {
+			problems = append(problems, problem(e.node, fmt.Sprintf("role of cluster %q with organizations %q is already defined at line %d", e.config.ID, orgs, n.Line)))
+			continue
+		}
+		seen[k] = e.node
+	}
+
+	return problems
+}
+
+func parseBool(n *yaml.Node) (bool, string) {
+	if n.Kind != yaml.ScalarNode || n.Tag != "!!bool" {
+		return false, "value must be a boolean"
+	}
+
+	var b bool
+	err := n.Decode(&b)
+	if err != nil {
+		return false, err.Error()
+	}
+
+	return b, ""
+}
+
+func parseString(n *yaml.Node) (string, string) {
+	if n.Kind != yaml.ScalarNode || n.Tag == "!!null" {
+		return "", "value must be a string"
+	}
+
+	return n.Value, ""
+}
+
+func parseStrings(n *yaml.Node) ([]string, string) {
+	if n.Kind != yaml.SequenceNode {
+		return nil, "value must be a list of strings"
+	}
+
+	var list []string
+	for _, item := range n.Content {
+		s, err := parseString(item)
+		if err != "" {
+			return nil, "value must be a list of strings"
+		}
+		list = append(list, s)
+	}
+
+	return list, ""
+}
+
+func problem(n *yaml.Node, message string) string {
+	return fmt.Sprintf("line %d, column %d: %s", n.Line, n.Column, message)
+}
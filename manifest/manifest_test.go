@@ -0,0 +1,167 @@
+package manifest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/vaultrole"
+	"github.com/giantswarm/vaultrole/vaultroletest"
+)
+
+func Test_Load(t *testing.T) {
+	testCases := []struct {
+		name             string
+		input            string
+		expectedConfigs  []vaultrole.CreateConfig
+		expectedProblems []string
+	}{
+		{
+			name: "case 0: YAML manifest with multiple documents",
+			input: `roles:
+- clusterID: al9qy
+  organizations:
+  - api
+  - system:masters
+  altNames:
+  - kubernetes
+  ttl: 8640h
+  allowBareDomains: true
+  allowSubdomains: true
+---
+roles:
+- clusterID: f6b2k
+  namespace: team-a
+  profile: api
+`,
+			expectedConfigs: []vaultrole.CreateConfig{
+				{
+					AllowBareDomains: true,
+					AllowSubdomains:  true,
+					AltNames:         []string{"kubernetes"},
+					ID:               "al9qy",
+					Organizations:    []string{"api", "system:masters"},
+					TTL:              "8640h",
+				},
+				{
+					ID:        "f6b2k",
+					Namespace: "team-a",
+					Profile:   "api",
+				},
+			},
+		},
+		{
+			name: "case 1: JSON manifest",
+			input: `{
+  "roles": [
+    {"clusterID": "al9qy", "ttl": "1h", "allowSubdomains": true}
+  ]
+}`,
+			expectedConfigs: []vaultrole.CreateConfig{
+				{
+					AllowSubdomains: true,
+					ID:              "al9qy",
+					TTL:             "1h",
+				},
+			},
+		},
+		{
+			name: "case 2: invalid fields are reported with their location",
+			input: `roles:
+- clusterID: al9qy
+  ttls: 1h
+  allowBareDomains: yes please
+- organizations: api
+  ttl: forever
+`,
+			expectedProblems: []string{
+				`line 3, column 3: unknown field "ttls"`,
+				`line 4, column 21: value must be a boolean`,
+				`line 5, column 18: value must be a list of strings`,
+				`line 6, column 8: ttl must be a duration like 8640h, got "forever"`,
+				`line 5, column 3: clusterID must not be empty`,
+			},
+		},
+		{
+			name: "case 3: duplicate roles are reported",
+			input: `roles:
+- clusterID: al9qy
+  organizations: [api, system:masters]
+- clusterID: al9qy
+  organizations: [system:masters, api]
+`,
+			expectedProblems: []string{
+				`line 4, column 3: role of cluster "al9qy" with organizations ["api" "system:masters"] is already defined at line 2`,
+			},
+		},
+		{
+			name:  "case 4: unknown top level fields are reported",
+			input: `role: []`,
+			expectedProblems: []string{
+				`line 1, column 1: unknown field "role"`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			configs, err := Load([]byte(tc.input))
+
+			if tc.expectedProblems == nil {
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+				if !reflect.DeepEqual(configs, tc.expectedConfigs) {
+					t.Fatalf("configs == %#v, want %#v", configs, tc.expectedConfigs)
+				}
+				return
+			}
+
+			if !IsInvalidManifest(err) {
+				t.Fatalf("error == %#v, want invalid manifest", err)
+			}
+			for _, p := range tc.expectedProblems {
+				if !strings.Contains(err.Error(), p) {
+					t.Fatalf("error == %q, want problem %q", err.Error(), p)
+				}
+			}
+		})
+	}
+}
+
+func Test_Reconcile(t *testing.T) {
+	s := vaultroletest.NewServer()
+	defer s.Close()
+	s.Mount("", "pki-al9qy")
+
+	r, err := s.NewVaultRole(vaultrole.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := `roles:
+- clusterID: al9qy
+  ttl: 1h
+- clusterID: al9qy
+  organizations: [api]
+  ttl: 1h
+`
+
+	results, err := Reconcile(r, []byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || !results[0].Created || !results[1].Created {
+		t.Fatalf("expected 2 created roles got %#v", results)
+	}
+
+	for _, c := range []vaultrole.ExistsConfig{{ID: "al9qy"}, {ID: "al9qy", Organizations: []string{"api"}}} {
+		exists, err := r.Exists(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !exists {
+			t.Fatalf("expected role %#v to exist", c)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	vaultclient "github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/vaultrole"
+)
+
+type LoggingConfig struct {
+	Logger micrologger.Logger
+}
+
+// NewLogging returns a middleware logging every request along with its
+// duration and error. Request data is not logged, since it may contain
+// secrets.
+func NewLogging(config LoggingConfig) (vaultrole.Middleware, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	m := Func(func(ctx context.Context, req Request, next Handler) (*vaultclient.Secret, error) {
+		start := time.Now()
+		secret, err := next(ctx, req)
+
+		if err != nil {
+			config.Logger.LogCtx(ctx, "level", "debug", "message", "Vault request failed", "operation", req.Operation, "namespace", req.Namespace, "path", req.Path, "duration", time.Since(start).String(), "stack", microerror.JSON(err))
+		} else {
+			config.Logger.LogCtx(ctx, "level", "debug", "message", "Vault request succeeded", "operation", req.Operation, "namespace", req.Namespace, "path", req.Path, "duration", time.Since(start).String())
+		}
+
+		return secret, err
+	})
+
+	return m, nil
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	vaultclient "github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/vaultrole"
+)
+
+// Observation describes a completed request, as passed to
+// MetricsConfig.Observe.
+type Observation struct {
+	Request  Request
+	Duration time.Duration
+	Error    error
+}
+
+type MetricsConfig struct {
+	// Observe is called with every completed request, e.g. in order to update
+	// a Prometheus histogram. It must be safe for concurrent use.
+	Observe func(o Observation)
+}
+
+// NewMetrics returns a middleware reporting the duration and error of every
+// request to MetricsConfig.Observe.
+func NewMetrics(config MetricsConfig) (vaultrole.Middleware, error) {
+	if config.Observe == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Observe must not be empty")
+	}
+
+	m := Func(func(ctx context.Context, req Request, next Handler) (*vaultclient.Secret, error) {
+		start := time.Now()
+		secret, err := next(ctx, req)
+
+		config.Observe(Observation{
+			Request:  req,
+			Duration: time.Since(start),
+			Error:    err,
+		})
+
+		return secret, err
+	})
+
+	return m, nil
+}
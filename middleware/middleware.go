@@ -0,0 +1,84 @@
+// Package middleware provides vaultrole.Middleware implementations logging,
+// measuring, retrying and recording the requests VaultRole issues. Middlewares
+// are applied using vaultrole.ChainLogicalClient.
+//
+//	c := vaultrole.ChainLogicalClient(
+//		vaultrole.NewLogicalClient(vaultClient),
+//		logging,
+//		metrics,
+//		retry,
+//	)
+package middleware
+
+import (
+	"context"
+
+	vaultclient "github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/vaultrole"
+)
+
+const (
+	OperationDelete = "delete"
+	OperationList   = "list"
+	OperationRead   = "read"
+	OperationWrite  = "write"
+)
+
+// Request is a request issued through a vaultrole.LogicalClient. Data is only
+// set for write requests.
+type Request struct {
+	Operation string
+	Namespace string
+	Path      string
+	Data      map[string]interface{}
+}
+
+// Handler issues the given request.
+type Handler func(ctx context.Context, req Request) (*vaultclient.Secret, error)
+
+// Func returns a middleware executing fn for every request, regardless of its
+// operation. fn is responsible for calling next in order to issue the request.
+func Func(fn func(ctx context.Context, req Request, next Handler) (*vaultclient.Secret, error)) vaultrole.Middleware {
+	return func(next vaultrole.LogicalClient) vaultrole.LogicalClient {
+		return &funcClient{
+			fn:   fn,
+			next: next,
+		}
+	}
+}
+
+type funcClient struct {
+	fn   func(ctx context.Context, req Request, next Handler) (*vaultclient.Secret, error)
+	next vaultrole.LogicalClient
+}
+
+func (c *funcClient) Delete(ctx context.Context, namespace string, path string) (*vaultclient.Secret, error) {
+	return c.fn(ctx, Request{Operation: OperationDelete, Namespace: namespace, Path: path}, c.handle)
+}
+
+func (c *funcClient) List(ctx context.Context, namespace string, path string) (*vaultclient.Secret, error) {
+	return c.fn(ctx, Request{Operation: OperationList, Namespace: namespace, Path: path}, c.handle)
+}
+
+func (c *funcClient) Read(ctx context.Context, namespace string, path string) (*vaultclient.Secret, error) {
+	return c.fn(ctx, Request{Operation: OperationRead, Namespace: namespace, Path: path}, c.handle)
+}
+
+func (c *funcClient) Write(ctx context.Context, namespace string, path string, data map[string]interface{}) (*vaultclient.Secret, error) {
+	return c.fn(ctx, Request{Operation: OperationWrite, Namespace: namespace, Path: path, Data: data}, c.handle)
+}
+
+// handle issues the given request using the wrapped client.
+func (c *funcClient) handle(ctx context.Context, req Request) (*vaultclient.Secret, error) {
+	switch req.Operation {
+	case OperationDelete:
+		return c.next.Delete(ctx, req.Namespace, req.Path)
+	case OperationList:
+		return c.next.List(ctx, req.Namespace, req.Path)
+	case OperationRead:
+		return c.next.Read(ctx, req.Namespace, req.Path)
+	default:
+		return c.next.Write(ctx, req.Namespace, req.Path, req.Data)
+	}
+}
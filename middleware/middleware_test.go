@@ -0,0 +1,205 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	vaultclient "github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/vaultrole"
+	"github.com/giantswarm/vaultrole/key"
+	"github.com/giantswarm/vaultrole/vaultroletest"
+)
+
+func Test_Middleware_VaultRole(t *testing.T) {
+	s := vaultroletest.NewServer()
+	defer s.Close()
+	s.Mount("team-a", "pki-al9qy")
+
+	client, err := s.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mutex sync.Mutex
+	var observations []Observation
+	metrics, err := NewMetrics(MetricsConfig{
+		Observe: func(o Observation) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			observations = append(observations, o)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logging, err := NewLogging(LoggingConfig{Logger: microloggertest.New()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	retry, err := NewRetry(RetryConfig{Logger: microloggertest.New()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := NewRecorder()
+
+	r, err := vaultrole.New(vaultrole.Config{
+		Logger: microloggertest.New(),
+		LogicalClient: vaultrole.ChainLogicalClient(
+			vaultrole.NewLogicalClient(client),
+			logging,
+			metrics,
+			retry,
+			recorder.Middleware(),
+		),
+		CommonNameFormat: "%s.g8s.gigantic.io",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Create(vaultrole.CreateConfig{ID: "al9qy", Namespace: "team-a", Organizations: []string{"api"}, TTL: "1h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var requests []Request
+	for _, rec := range recorder.Recordings() {
+		if rec.Error != nil {
+			t.Fatalf("expected request %#v to succeed, got %#v", rec.Request, rec.Error)
+		}
+		rec.Request.Data = nil
+		requests = append(requests, rec.Request)
+	}
+
+	expected := []Request{
+		{Operation: OperationList, Namespace: "team-a", Path: key.ListRolesPath("al9qy")},
+		{Operation: OperationWrite, Namespace: "team-a", Path: key.WriteRolePath("al9qy", []string{"api"})},
+	}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Fatalf("requests == %#v, want %#v", requests, expected)
+	}
+	if len(observations) != len(expected) {
+		t.Fatalf("expected %d observations, got %d", len(expected), len(observations))
+	}
+
+	if _, ok := s.Data("team-a", key.WriteRolePath("al9qy", []string{"api"})); !ok {
+		t.Fatal("expected role to be written to namespace team-a")
+	}
+
+	recorder.Reset()
+	if len(recorder.Recordings()) != 0 {
+		t.Fatal("expected no recordings after reset")
+	}
+}
+
+func Test_NewRetry(t *testing.T) {
+	testCases := []struct {
+		name          string
+		errs          []error
+		expectedCalls int
+		expectedError bool
+	}{
+		{
+			name:          "case 0: success",
+			errs:          []error{nil},
+			expectedCalls: 1,
+		},
+		{
+			name:          "case 1: transient errors are retried",
+			errs:          []error{responseError(http.StatusServiceUnavailable), responseError(http.StatusTooManyRequests), nil},
+			expectedCalls: 3,
+		},
+		{
+			name:          "case 2: attempts are limited",
+			errs:          []error{responseError(http.StatusBadGateway), responseError(http.StatusBadGateway), responseError(http.StatusBadGateway), nil},
+			expectedCalls: 3,
+			expectedError: true,
+		},
+		{
+			name:          "case 3: denied requests are not retried",
+			errs:          []error{responseError(http.StatusForbidden), nil},
+			expectedCalls: 1,
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			retry, err := NewRetry(RetryConfig{Logger: microloggertest.New(), Backoff: time.Millisecond})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			f := &failingClient{errs: tc.errs}
+			_, err = retry(f).Read(context.Background(), "", "pki-al9qy/roles/role-al9qy")
+
+			if f.calls != tc.expectedCalls {
+				t.Fatalf("calls == %d, want %d", f.calls, tc.expectedCalls)
+			}
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("error == %#v, want error %t", err, tc.expectedError)
+			}
+		})
+	}
+}
+
+func Test_NewRetry_Context(t *testing.T) {
+	retry, err := NewRetry(RetryConfig{Logger: microloggertest.New(), Backoff: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	f := &failingClient{errs: []error{responseError(http.StatusServiceUnavailable), nil}}
+	_, err = retry(f).Read(ctx, "", "pki-al9qy/roles/role-al9qy")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if f.calls != 1 {
+		t.Fatalf("calls == %d, want %d", f.calls, 1)
+	}
+}
+
+func responseError(statusCode int) error {
+	return &vaultclient.ResponseError{StatusCode: statusCode}
+}
+
+// failingClient returns the given errors one after another.
+type failingClient struct {
+	calls int
+	errs  []error
+}
+
+func (c *failingClient) Delete(ctx context.Context, namespace string, path string) (*vaultclient.Secret, error) {
+	return c.next()
+}
+
+func (c *failingClient) List(ctx context.Context, namespace string, path string) (*vaultclient.Secret, error) {
+	return c.next()
+}
+
+func (c *failingClient) Read(ctx context.Context, namespace string, path string) (*vaultclient.Secret, error) {
+	return c.next()
+}
+
+func (c *failingClient) Write(ctx context.Context, namespace string, path string, data map[string]interface{}) (*vaultclient.Secret, error) {
+	return c.next()
+}
+
+func (c *failingClient) next() (*vaultclient.Secret, error) {
+	err := c.errs[c.calls]
+	c.calls++
+	if err != nil {
+		return nil, err
+	}
+
+	return &vaultclient.Secret{}, nil
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+
+	vaultclient "github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/vaultrole"
+)
+
+// Recording is a request recorded by Recorder along with its response.
+type Recording struct {
+	Request Request
+	Secret  *vaultclient.Secret
+	Error   error
+}
+
+// Recorder records every request issued through its middleware, e.g. in
+// order to assert requests in tests or to inspect them when debugging.
+// Recorder is safe for concurrent use.
+type Recorder struct {
+	mutex      sync.Mutex
+	recordings []Recording
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Middleware returns the middleware recording requests.
+func (r *Recorder) Middleware() vaultrole.Middleware {
+	return Func(func(ctx context.Context, req Request, next Handler) (*vaultclient.Secret, error) {
+		secret, err := next(ctx, req)
+
+		r.mutex.Lock()
+		r.recordings = append(r.recordings, Recording{
+			Request: req,
+			Secret:  secret,
+			Error:   err,
+		})
+		r.mutex.Unlock()
+
+		return secret, err
+	})
+}
+
+// Recordings returns the requests recorded so far, in the order they
+// completed.
+func (r *Recorder) Recordings() []Recording {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]Recording(nil), r.recordings...)
+}
+
+// Reset drops all recorded requests.
+func (r *Recorder) Reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.recordings = nil
+}
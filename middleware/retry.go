@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	vaultclient "github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/vaultrole"
+)
+
+type RetryConfig struct {
+	Logger micrologger.Logger
+
+	// Attempts is the maximum number of times a request is issued. Defaults to
+	// 3.
+	Attempts int
+	// Backoff is the time waited before the first retry. It doubles with
+	// every further retry. Defaults to 100 milliseconds.
+	Backoff time.Duration
+	// Retryable decides whether a failed request is retried. Defaults to
+	// IsRetryable.
+	Retryable func(err error) bool
+}
+
+// NewRetry returns a middleware retrying failed requests with exponential
+// backoff. Retries stop as soon as the context of the request is done.
+func NewRetry(config RetryConfig) (vaultrole.Middleware, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	if config.Attempts < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.Attempts must not be negative")
+	}
+	if config.Attempts == 0 {
+		config.Attempts = 3
+	}
+	if config.Backoff < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.Backoff must not be negative")
+	}
+	if config.Backoff == 0 {
+		config.Backoff = 100 * time.Millisecond
+	}
+	if config.Retryable == nil {
+		config.Retryable = IsRetryable
+	}
+
+	m := Func(func(ctx context.Context, req Request, next Handler) (*vaultclient.Secret, error) {
+		backoff := config.Backoff
+
+		for attempt := 1; ; attempt++ {
+			secret, err := next(ctx, req)
+			if err == nil || attempt >= config.Attempts || !config.Retryable(err) {
+				return secret, err
+			}
+
+			config.Logger.LogCtx(ctx, "level", "debug", "message", "retrying failed Vault request", "operation", req.Operation, "namespace", req.Namespace, "path", req.Path, "attempt", attempt, "backoff", backoff.String())
+
+			select {
+			case <-ctx.Done():
+				return nil, microerror.Mask(ctx.Err())
+			case <-time.After(backoff):
+			}
+
+			backoff *= 2
+		}
+	})
+
+	return m, nil
+}
+
+// IsRetryable returns true for errors which are likely to be transient. These
+// are Vault responses with status code 429 or 5xx and errors not caused by a
+// Vault response, e.g. connection failures. Denied requests are not retried,
+// since re-authentication is handled by vaultrole.Config.Authenticator.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if microerror.Cause(err) == context.Canceled || microerror.Cause(err) == context.DeadlineExceeded {
+		return false
+	}
+
+	responseError, ok := microerror.Cause(err).(*vaultclient.ResponseError)
+	if !ok {
+		return true
+	}
+
+	return responseError.StatusCode == http.StatusTooManyRequests || responseError.StatusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,132 @@
+package vaultrole
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/vaultrole/key"
+)
+
+type MigrateConfig struct {
+	ID        string
+	Namespace string
+}
+
+// MigrateResult describes a role which was stored in the legacy format.
+type MigrateResult struct {
+	Name string
+	// Fields are the names of the fields which were stored as comma joined
+	// strings and got rewritten as lists.
+	Fields []string
+}
+
+// Migrate rewrites every role of the PKI backend of the given cluster which
+// still stores list fields as comma joined strings, as done by older Vault
+// versions. All other fields of the role are written back unchanged, so that
+// the content of the role does not change. The returned results list the
+// migrated roles. In dry run mode nothing is written and the results list the
+// roles which would be migrated. Migrations do not emit audit events since the
+// content of the roles does not change.
+func (r *VaultRole) Migrate(config MigrateConfig) ([]MigrateResult, error) {
+	if config.ID == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.ID must not be empty")
+	}
+
+	names, err := r.listRoleNames(config.Namespace, config.ID)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var results []MigrateResult
+	for _, n := range names {
+		path := key.NamedRolePath(config.ID, n)
+
+		// The lock is taken directly instead of using pathLocker.Do, since
+		// concurrent migrations must not share the fields they report.
+		var fields []string
+		err := func() error {
+			unlock := r.locker.lock(config.Namespace + "/" + path)
+			defer unlock()
+
+			var secret *api.Secret
+			err := r.withReauth(func() error {
+				var err error
+				secret, err = r.logicalClient.Read(r.ctx, config.Namespace, path)
+				if err != nil {
+					return microerror.Mask(err)
+				}
+
+				return nil
+			})
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			// The role got deleted after listing it.
+			if secret == nil {
+				return nil
+			}
+
+			data := map[string]interface{}{}
+			for k, v := range secret.Data {
+				data[k] = v
+			}
+			// Older Vault versions stored the list fields tolerating comma
+			// joined strings as such.
+			for _, f := range roleSchema {
+				if f.Type != fieldTypeStringList || !f.ListOrString {
+					continue
+				}
+				s, ok := data[f.Name].(string)
+				if !ok {
+					continue
+				}
+
+				list := []string{}
+				if s != "" {
+					list = strings.Split(s, ",")
+				}
+				data[f.Name] = list
+				fields = append(fields, f.Name)
+			}
+
+			if len(fields) == 0 {
+				return nil
+			}
+
+			if r.dryRun {
+				r.logger.Log("level", "info", "message", "dry run, skipping migration of Vault role", "namespace", config.Namespace, "path", path)
+				return nil
+			}
+
+			err = r.withReauth(func() error {
+				_, err := r.logicalClient.Write(r.ctx, config.Namespace, path, data)
+				if err != nil {
+					return microerror.Mask(err)
+				}
+
+				return nil
+			})
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			r.logger.Log("level", "debug", "message", "migrated Vault role", "namespace", config.Namespace, "path", path)
+
+			return nil
+		}()
+		if err != nil {
+			return results, microerror.Mask(err)
+		}
+
+		if len(fields) > 0 {
+			sort.Strings(fields)
+			results = append(results, MigrateResult{Name: n, Fields: fields})
+		}
+	}
+
+	return results, nil
+}
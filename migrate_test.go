@@ -0,0 +1,105 @@
+package vaultrole_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/giantswarm/vaultrole"
+	"github.com/giantswarm/vaultrole/key"
+	"github.com/giantswarm/vaultrole/vaultroletest"
+)
+
+func Test_VaultRole_Migrate(t *testing.T) {
+	s := vaultroletest.NewServer()
+	defer s.Close()
+	s.Mount("", "pki-al9qy")
+
+	newVaultRole := func(dryRun bool) *vaultrole.VaultRole {
+		r, err := s.NewVaultRole(vaultrole.Config{DryRun: dryRun})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return r
+	}
+
+	r := newVaultRole(false)
+
+	err := r.Create(vaultrole.CreateConfig{ID: "al9qy", TTL: "1h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	orgs := []string{"api", "system:masters"}
+	path := key.WriteRolePath("al9qy", orgs)
+	s.SetData("", path, map[string]interface{}{
+		"allow_bare_domains": false,
+		"allow_subdomains":   true,
+		"allowed_domains":    "al9qy.g8s.gigantic.io,api.internal",
+		"organization":       "api,system:masters",
+		"ttl":                3600,
+	})
+
+	before, err := r.Search(vaultrole.SearchConfig{ID: "al9qy", Organizations: orgs})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []vaultrole.MigrateResult{
+		{
+			Name:   key.RoleName("al9qy", orgs),
+			Fields: []string{"allowed_domains", "organization"},
+		},
+	}
+
+	{
+		results, err := newVaultRole(true).Migrate(vaultrole.MigrateConfig{ID: "al9qy"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(results, expected) {
+			t.Fatalf("expected %#v got %#v", expected, results)
+		}
+
+		d, _ := s.Data("", path)
+		if _, ok := d["organization"].(string); !ok {
+			t.Fatalf("expected dry run to not migrate role")
+		}
+	}
+
+	{
+		results, err := r.Migrate(vaultrole.MigrateConfig{ID: "al9qy"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(results, expected) {
+			t.Fatalf("expected %#v got %#v", expected, results)
+		}
+
+		d, _ := s.Data("", path)
+		if _, ok := d["organization"].([]interface{}); !ok {
+			t.Fatalf("expected organization to be a list got %T", d["organization"])
+		}
+		if _, ok := d["allowed_domains"].([]interface{}); !ok {
+			t.Fatalf("expected allowed_domains to be a list got %T", d["allowed_domains"])
+		}
+	}
+
+	after, err := r.Search(vaultrole.SearchConfig{ID: "al9qy", Organizations: orgs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(before, after) {
+		t.Fatalf("expected role %#v got %#v", before, after)
+	}
+
+	{
+		results, err := r.Migrate(vaultrole.MigrateConfig{ID: "al9qy"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 0 {
+			t.Fatalf("expected no further migrations got %#v", results)
+		}
+	}
+}
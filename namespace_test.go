@@ -0,0 +1,89 @@
+package vaultrole_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/vaultrole"
+	"github.com/giantswarm/vaultrole/vaultroletest"
+)
+
+func Test_VaultRole_Namespace(t *testing.T) {
+	s := vaultroletest.NewServer()
+	defer s.Close()
+	s.Mount("team-a", "pki-al9qy")
+	s.Mount("team-b", "pki-al9qy")
+
+	r, err := s.NewVaultRole(vaultrole.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Create(vaultrole.CreateConfig{
+		AllowBareDomains: true,
+		AltNames:         []string{"kubernetes"},
+		ID:               "al9qy",
+		Namespace:        "team-a",
+		Organizations:    []string{"api"},
+		TTL:              "1h",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name           string
+		namespace      string
+		expectedExists bool
+	}{
+		{
+			name:           "case 0: namespace the role is created in",
+			namespace:      "team-a",
+			expectedExists: true,
+		},
+		{
+			name:           "case 1: other namespace",
+			namespace:      "team-b",
+			expectedExists: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			exists, err := r.Exists(vaultrole.ExistsConfig{ID: "al9qy", Namespace: tc.namespace, Organizations: []string{"api"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exists != tc.expectedExists {
+				t.Fatalf("exists == %t, want %t", exists, tc.expectedExists)
+			}
+		})
+	}
+
+	{
+		role, err := r.Search(vaultrole.SearchConfig{ID: "al9qy", Namespace: "team-a", Organizations: []string{"api"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := vaultrole.Role{
+			AllowBareDomains: true,
+			AltNames:         []string{"kubernetes"},
+			CommonName:       "al9qy.g8s.gigantic.io",
+			ID:               "al9qy",
+			Organizations:    []string{"api"},
+			TTL:              time.Hour,
+		}
+		if !reflect.DeepEqual(role, expected) {
+			t.Fatalf("Role == %#v, want %#v", role, expected)
+		}
+	}
+
+	{
+		_, err := r.Search(vaultrole.SearchConfig{ID: "al9qy", Namespace: "team-b", Organizations: []string{"api"}})
+		if !vaultrole.IsNotFound(err) {
+			t.Fatalf("expected not found error got %#v", err)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package vaultrole
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/vaultrole/key"
+)
+
+// Plan merges the profile referenced by the given config, validates the
+// config and computes the request which creating or
+// updating the role would write to Vault, without issuing any request. The
+// returned request can be inspected, snapshot tested or audited, and executed
+// using Apply.
+func (r *VaultRole) Plan(config PlanConfig) (WriteRequest, error) {
+	merged, err := r.mergeProfile(writeConfig(config))
+	if err != nil {
+		return WriteRequest{}, microerror.Mask(err)
+	}
+	config = PlanConfig(merged)
+
+	err = validateWriteConfig(merged)
+	if err != nil {
+		return WriteRequest{}, microerror.Mask(err)
+	}
+
+	commonName, err := r.commonName(config.CommonNameFormat, config.CommonNameValues, config.ID)
+	if err != nil {
+		return WriteRequest{}, microerror.Mask(err)
+	}
+
+	req := WriteRequest{
+		Namespace: config.Namespace,
+		Path:      key.WriteRolePath(config.ID, config.Organizations),
+		Payload:   writePayload(writeConfig(config), commonName),
+	}
+
+	return req, nil
+}
+
+// writePayload returns the payload written to Vault for the given config and
+// common name.
+func writePayload(config writeConfig, commonName string) WritePayload {
+	p := WritePayload{
+		AllowBareDomains: config.AllowBareDomains,
+		AllowSubdomains:  config.AllowSubdomains,
+		AllowedDomains:   key.JoinAllowedDomains(commonName, config.AltNames),
+		Organization:     strings.Join(config.Organizations, ","),
+		TTL:              config.TTL,
+	}
+
+	return p
+}
+
+// Apply executes the given request as computed by Plan. Apply is serialised
+// with other operations on the same role path. Unlike Create and Update, Apply
+// does not check whether the role exists. In case Config.ManagePolicies is set,
+// the policy of the role is written along with it, unless the request writes a
+// role which is not named after its organizations, see key.RoleName.
+func (r *VaultRole) Apply(req WriteRequest) error {
+	err := r.locker.Do("apply", req.Namespace+"/"+req.Path, req, func() error {
+		ID, organizations, ok := managedRole(req)
+		if ok {
+			err := r.writePolicy(req.Namespace, ID, organizations)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+		} else if r.managePolicies {
+			r.logger.Log("level", "debug", "message", "skipping write of Vault policy for role not named after its organizations", "namespace", req.Namespace, "path", req.Path)
+		}
+
+		err := r.apply(req)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (r *VaultRole) apply(req WriteRequest) error {
+	v, err := roleSchema.encode(req.Payload)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	for k, e := range req.Payload.Extra {
+		if !roleSchema.has(k) {
+			v[k] = e
+		}
+	}
+
+	if r.dryRun {
+		b, err := json.Marshal(req.Payload)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		r.logger.Log("level", "info", "message", "dry run, skipping write of Vault role", "namespace", req.Namespace, "path", req.Path, "payload", string(b))
+
+		return nil
+	}
+
+	err = r.withReauth(func() error {
+		_, err := r.logicalClient.Write(r.ctx, req.Namespace, req.Path, v)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// managedRole returns the cluster ID and organizations of the role written by
+// the given request, in case the role is named after its organizations the
+// way Plan computes the path.
+func managedRole(req WriteRequest) (string, []string, bool) {
+	parts := strings.Split(req.Path, "/")
+	if len(parts) != 3 || !strings.HasPrefix(parts[0], "pki-") || parts[1] != "roles" {
+		return "", nil, false
+	}
+	ID := strings.TrimPrefix(parts[0], "pki-")
+
+	var organizations []string
+	if req.Payload.Organization != "" {
+		organizations = strings.Split(req.Payload.Organization, ",")
+	}
+
+	if key.WriteRolePath(ID, append([]string(nil), organizations...)) != req.Path {
+		return "", nil, false
+	}
+
+	return ID, organizations, true
+}
@@ -0,0 +1,87 @@
+package vaultrole
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func Test_VaultRole_Plan(t *testing.T) {
+	testCases := []struct {
+		name         string
+		config       PlanConfig
+		expectedJSON string
+		errorMatcher func(error) bool
+	}{
+		{
+			name: "case 0: role without organizations",
+			config: PlanConfig{
+				AllowBareDomains: true,
+				AllowSubdomains:  true,
+				AltNames:         []string{"kubernetes", "kubernetes.default.svc.cluster.local"},
+				ID:               "al9qy",
+				TTL:              "8640h",
+			},
+			expectedJSON: `{"path":"pki-al9qy/roles/role-al9qy","payload":{"allow_bare_domains":true,"allow_subdomains":true,"allowed_domains":"al9qy.g8s.gigantic.io,kubernetes,kubernetes.default.svc.cluster.local","organization":"","ttl":"8640h"}}`,
+			errorMatcher: nil,
+		},
+		{
+			name: "case 1: role with organizations in namespace",
+			config: PlanConfig{
+				ID:            "al9qy",
+				Namespace:     "team-a",
+				Organizations: []string{"system:masters", "api"},
+				TTL:           "1h",
+			},
+			expectedJSON: `{"namespace":"team-a","path":"pki-al9qy/roles/role-org-7395c031992f478e2e0e8d3198272008d407e1bc209c0cd52048fdebdd4ac1e0afd1d904044d9a9a2b0fe515579a56a4daf2aea7092518218ef985371890109f","payload":{"allow_bare_domains":false,"allow_subdomains":false,"allowed_domains":"al9qy.g8s.gigantic.io","organization":"api,system:masters","ttl":"1h"}}`,
+			errorMatcher: nil,
+		},
+		{
+			name: "case 2: invalid TTL causes invalidConfigError",
+			config: PlanConfig{
+				ID:  "al9qy",
+				TTL: "forever",
+			},
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "case 3: missing ID causes invalidConfigError",
+			config: PlanConfig{
+				TTL: "1h",
+			},
+			errorMatcher: IsInvalidConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &VaultRole{
+				commonNameFormat: "%s.g8s.gigantic.io",
+			}
+
+			req, err := r.Plan(tc.config)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if tc.errorMatcher != nil {
+				return
+			}
+
+			b, err := json.Marshal(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tc.expectedJSON {
+				t.Fatalf("WriteRequest == %s, want %s", b, tc.expectedJSON)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package vaultrole
+
+import (
+	"fmt"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/vaultrole/key"
+)
+
+// RenderPolicy renders the HCL of the ACL policy granting issuing and signing
+// certificates using the role of the given cluster ID and organizations.
+func RenderPolicy(ID string, organizations []string) string {
+	orgs := append([]string(nil), organizations...)
+
+	var s string
+	for _, p := range []string{key.IssuePath(ID, orgs), key.SignPath(ID, orgs)} {
+		if s != "" {
+			s += "\n"
+		}
+		s += fmt.Sprintf("path %q {\n  capabilities = [\"create\", \"update\"]\n}\n", p)
+	}
+
+	return s
+}
+
+// writePolicy writes the ACL policy of the role of the given cluster ID and
+// organizations in case Config.ManagePolicies is set.
+func (r *VaultRole) writePolicy(namespace string, ID string, organizations []string) error {
+	if !r.managePolicies {
+		return nil
+	}
+
+	p := key.PolicyPath(key.PolicyName(ID, append([]string(nil), organizations...)))
+	v := map[string]interface{}{
+		"policy": RenderPolicy(ID, organizations),
+	}
+
+	if r.dryRun {
+		r.logger.Log("level", "info", "message", "dry run, skipping write of Vault policy", "namespace", namespace, "path", p, "policy", v["policy"])
+		return nil
+	}
+
+	err := r.withReauth(func() error {
+		_, err := r.logicalClient.Write(r.ctx, namespace, p, v)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// deletePolicy deletes the ACL policy of the role of the given cluster ID and
+// organizations in case Config.ManagePolicies is set.
+func (r *VaultRole) deletePolicy(namespace string, ID string, organizations []string) error {
+	if !r.managePolicies {
+		return nil
+	}
+
+	p := key.PolicyPath(key.PolicyName(ID, append([]string(nil), organizations...)))
+
+	if r.dryRun {
+		r.logger.Log("level", "info", "message", "dry run, skipping delete of Vault policy", "namespace", namespace, "path", p)
+		return nil
+	}
+
+	err := r.withReauth(func() error {
+		_, err := r.logicalClient.Delete(r.ctx, namespace, p)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
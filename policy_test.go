@@ -0,0 +1,233 @@
+package vaultrole_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	vaultclient "github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/vaultrole"
+	"github.com/giantswarm/vaultrole/key"
+	"github.com/giantswarm/vaultrole/middleware"
+	"github.com/giantswarm/vaultrole/vaultroletest"
+)
+
+func Test_RenderPolicy(t *testing.T) {
+	expected := `path "pki-al9qy/issue/role-al9qy" {
+  capabilities = ["create", "update"]
+}
+
+path "pki-al9qy/sign/role-al9qy" {
+  capabilities = ["create", "update"]
+}
+`
+
+	policy := vaultrole.RenderPolicy("al9qy", nil)
+	if policy != expected {
+		t.Fatalf("policy == %q, want %q", policy, expected)
+	}
+}
+
+func Test_VaultRole_ManagePolicies(t *testing.T) {
+	s := vaultroletest.NewServer()
+	defer s.Close()
+	s.Mount("", "pki-al9qy")
+
+	r, err := s.NewVaultRole(vaultrole.Config{ManagePolicies: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	orgs := []string{"system:masters", "api"}
+	path := key.PolicyPath(key.PolicyName("al9qy", []string{"api", "system:masters"}))
+
+	err = r.Create(vaultrole.CreateConfig{ID: "al9qy", Organizations: orgs, TTL: "1h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	{
+		d, ok := s.Data("", path)
+		if !ok {
+			t.Fatalf("expected policy to be written to %s", path)
+		}
+		if d["policy"] != vaultrole.RenderPolicy("al9qy", orgs) {
+			t.Fatalf("unexpected policy %q", d["policy"])
+		}
+	}
+
+	// Updates restore policies changed in Vault.
+	s.SetData("", path, map[string]interface{}{"policy": ""})
+	err = r.Update(vaultrole.UpdateConfig{ID: "al9qy", Organizations: orgs, TTL: "2h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	{
+		d, _ := s.Data("", path)
+		if d["policy"] != vaultrole.RenderPolicy("al9qy", orgs) {
+			t.Fatalf("unexpected policy %q", d["policy"])
+		}
+	}
+
+	err = r.Delete(vaultrole.DeleteConfig{ID: "al9qy", Organizations: orgs})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := s.Data("", path); ok {
+		t.Fatalf("expected policy to be deleted")
+	}
+}
+
+func Test_VaultRole_ManagePolicies_Retry(t *testing.T) {
+	s := vaultroletest.NewServer()
+	defer s.Close()
+	s.Mount("", "pki-al9qy")
+
+	client, err := s.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// failPolicies makes requests against policy paths fail while set.
+	var failPolicies bool
+	fail := middleware.Func(func(ctx context.Context, req middleware.Request, next middleware.Handler) (*vaultclient.Secret, error) {
+		if failPolicies && strings.HasPrefix(req.Path, "sys/policies/") {
+			return nil, &vaultclient.ResponseError{StatusCode: http.StatusInternalServerError}
+		}
+		return next(ctx, req)
+	})
+
+	r, err := s.NewVaultRole(vaultrole.Config{
+		LogicalClient:  vaultrole.ChainLogicalClient(vaultrole.NewLogicalClient(client), fail),
+		ManagePolicies: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	orgs := []string{"api"}
+	rolePath := key.WriteRolePath("al9qy", orgs)
+	policyPath := key.PolicyPath(key.PolicyName("al9qy", orgs))
+
+	// A failed policy write leaves no role behind, so Create can be retried.
+	failPolicies = true
+	err = r.Create(vaultrole.CreateConfig{ID: "al9qy", Organizations: orgs, TTL: "1h"})
+	if err == nil {
+		t.Fatal("expected create to fail")
+	}
+	if _, ok := s.Data("", rolePath); ok {
+		t.Fatal("expected role not to be written")
+	}
+
+	failPolicies = false
+	err = r.Create(vaultrole.CreateConfig{ID: "al9qy", Organizations: orgs, TTL: "1h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Data("", policyPath); !ok {
+		t.Fatal("expected policy to be written")
+	}
+
+	// Creating an existing role reconciles its policy.
+	s.SetData("", policyPath, map[string]interface{}{"policy": ""})
+	err = r.Create(vaultrole.CreateConfig{ID: "al9qy", Organizations: orgs, TTL: "1h"})
+	if !vaultrole.IsAlreadyExists(err) {
+		t.Fatalf("expected already exists error got %#v", err)
+	}
+	if d, _ := s.Data("", policyPath); d["policy"] != vaultrole.RenderPolicy("al9qy", orgs) {
+		t.Fatalf("unexpected policy %q", d["policy"])
+	}
+
+	// A failed policy delete leaves the role in place, so Delete can be
+	// retried.
+	failPolicies = true
+	err = r.Delete(vaultrole.DeleteConfig{ID: "al9qy", Organizations: orgs})
+	if err == nil {
+		t.Fatal("expected delete to fail")
+	}
+	if _, ok := s.Data("", rolePath); !ok {
+		t.Fatal("expected role not to be deleted")
+	}
+
+	failPolicies = false
+	err = r.Delete(vaultrole.DeleteConfig{ID: "al9qy", Organizations: orgs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Data("", policyPath); ok {
+		t.Fatal("expected policy to be deleted")
+	}
+
+	// Deleting a missing role cleans up its policy.
+	s.SetData("", policyPath, map[string]interface{}{"policy": ""})
+	err = r.Delete(vaultrole.DeleteConfig{ID: "al9qy", Organizations: orgs})
+	if !vaultrole.IsNotFound(err) {
+		t.Fatalf("expected not found error got %#v", err)
+	}
+	if _, ok := s.Data("", policyPath); ok {
+		t.Fatal("expected orphaned policy to be deleted")
+	}
+}
+
+func Test_VaultRole_ManagePolicies_Apply(t *testing.T) {
+	s := vaultroletest.NewServer()
+	defer s.Close()
+	s.Mount("", "pki-al9qy")
+
+	r, err := s.NewVaultRole(vaultrole.Config{ManagePolicies: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name          string
+		path          func(req vaultrole.WriteRequest) string
+		expectPolicy  bool
+		organizations []string
+	}{
+		{
+			name:          "case 0: role named after its organizations",
+			organizations: []string{"api"},
+			expectPolicy:  true,
+		},
+		{
+			name:          "case 1: base role",
+			organizations: nil,
+			expectPolicy:  true,
+		},
+		{
+			name:          "case 2: role not named after its organizations",
+			organizations: []string{"etcd"},
+			path: func(req vaultrole.WriteRequest) string {
+				return key.NamedRolePath("al9qy", "custom")
+			},
+			expectPolicy: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := r.Plan(vaultrole.PlanConfig{ID: "al9qy", Organizations: tc.organizations, TTL: "1h"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.path != nil {
+				req.Path = tc.path(req)
+			}
+
+			err = r.Apply(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, ok := s.Data("", key.PolicyPath(key.PolicyName("al9qy", tc.organizations)))
+			if ok != tc.expectPolicy {
+				t.Fatalf("policy written == %t, want %t", ok, tc.expectPolicy)
+			}
+		})
+	}
+}
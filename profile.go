@@ -0,0 +1,117 @@
+package vaultrole
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+// Fields of a Profile which can be listed in Profile.Overrides.
+const (
+	ProfileFieldAllowBareDomains = "AllowBareDomains"
+	ProfileFieldAllowSubdomains  = "AllowSubdomains"
+	ProfileFieldAltNames         = "AltNames"
+	ProfileFieldOrganizations    = "Organizations"
+	ProfileFieldTTL              = "TTL"
+)
+
+// Profile defines default role fields for a common kind of certificate, e.g.
+// API server or etcd certificates. Operations referencing a profile by name
+// use its fields for every field they leave empty. Fields set by the operation
+// are only accepted in case they are listed in Overrides. Since booleans cannot
+// be left empty, AllowBareDomains and AllowSubdomains only count as set when
+// true.
+type Profile struct {
+	AllowBareDomains bool
+	AllowSubdomains  bool
+	AltNames         []string
+	Organizations    []string
+	TTL              string
+
+	// Overrides lists the fields operations may set to deviate from the
+	// profile, e.g. ProfileFieldAltNames.
+	Overrides []string
+}
+
+// MergeProfile returns the given config with the fields of the profile it
+// references merged in. The merged config is validated. In case the config
+// does not reference a profile, it is only validated.
+func (r *VaultRole) MergeProfile(config CreateConfig) (CreateConfig, error) {
+	merged, err := r.mergeProfile(writeConfig(config))
+	if err != nil {
+		return CreateConfig{}, microerror.Mask(err)
+	}
+
+	err = validateWriteConfig(merged)
+	if err != nil {
+		return CreateConfig{}, microerror.Mask(err)
+	}
+
+	return CreateConfig(merged), nil
+}
+
+// mergeProfile merges the fields of the profile referenced by the given config
+// into the config. The returned config does not reference the profile anymore.
+func (r *VaultRole) mergeProfile(config writeConfig) (writeConfig, error) {
+	if config.Profile == "" {
+		return config, nil
+	}
+
+	p, ok := r.profiles[config.Profile]
+	if !ok {
+		return writeConfig{}, microerror.Maskf(invalidConfigError, "profile '%s' does not exist", config.Profile)
+	}
+
+	overrides := map[string]bool{}
+	for _, o := range p.Overrides {
+		overrides[o] = true
+	}
+
+	merged := writeConfig{
+		AllowBareDomains: p.AllowBareDomains,
+		AllowSubdomains:  p.AllowSubdomains,
+		AltNames:         p.AltNames,
+		CommonNameFormat: config.CommonNameFormat,
+		CommonNameValues: config.CommonNameValues,
+		ID:               config.ID,
+		Namespace:        config.Namespace,
+		Organizations:    p.Organizations,
+		TTL:              p.TTL,
+	}
+
+	if config.AllowBareDomains && !p.AllowBareDomains {
+		if !overrides[ProfileFieldAllowBareDomains] {
+			return writeConfig{}, microerror.Maskf(invalidConfigError, "profile '%s' does not allow overriding %s", config.Profile, ProfileFieldAllowBareDomains)
+		}
+		merged.AllowBareDomains = true
+	}
+	if config.AllowSubdomains && !p.AllowSubdomains {
+		if !overrides[ProfileFieldAllowSubdomains] {
+			return writeConfig{}, microerror.Maskf(invalidConfigError, "profile '%s' does not allow overriding %s", config.Profile, ProfileFieldAllowSubdomains)
+		}
+		merged.AllowSubdomains = true
+	}
+	if len(config.AltNames) != 0 {
+		if !overrides[ProfileFieldAltNames] {
+			return writeConfig{}, microerror.Maskf(invalidConfigError, "profile '%s' does not allow overriding %s", config.Profile, ProfileFieldAltNames)
+		}
+		merged.AltNames = config.AltNames
+	}
+	if len(config.Organizations) != 0 {
+		if !overrides[ProfileFieldOrganizations] {
+			return writeConfig{}, microerror.Maskf(invalidConfigError, "profile '%s' does not allow overriding %s", config.Profile, ProfileFieldOrganizations)
+		}
+		merged.Organizations = config.Organizations
+	}
+	if config.TTL != "" {
+		if !overrides[ProfileFieldTTL] {
+			return writeConfig{}, microerror.Maskf(invalidConfigError, "profile '%s' does not allow overriding %s", config.Profile, ProfileFieldTTL)
+		}
+		merged.TTL = config.TTL
+	}
+
+	// The lists are copied since computing role names sorts organizations in
+	// place, which must not affect the registered profile.
+	merged.AltNames = append([]string(nil), merged.AltNames...)
+	merged.Organizations = append([]string(nil), merged.Organizations...)
+
+	return merged, nil
+}
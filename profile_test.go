@@ -0,0 +1,145 @@
+package vaultrole
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_VaultRole_MergeProfile(t *testing.T) {
+	profiles := map[string]Profile{
+		"api": {
+			AllowBareDomains: true,
+			AllowSubdomains:  true,
+			AltNames:         []string{"kubernetes"},
+			Organizations:    []string{"api", "system:masters"},
+			TTL:              "8640h",
+			Overrides:        []string{ProfileFieldAltNames},
+		},
+	}
+
+	testCases := []struct {
+		name           string
+		config         CreateConfig
+		expectedConfig CreateConfig
+		errorMatcher   func(error) bool
+	}{
+		{
+			name: "case 0: config without profile is returned as is",
+			config: CreateConfig{
+				ID:  "al9qy",
+				TTL: "1h",
+			},
+			expectedConfig: CreateConfig{
+				ID:  "al9qy",
+				TTL: "1h",
+			},
+			errorMatcher: nil,
+		},
+		{
+			name: "case 1: empty fields are taken from the profile",
+			config: CreateConfig{
+				ID:        "al9qy",
+				Namespace: "team-a",
+				Profile:   "api",
+			},
+			expectedConfig: CreateConfig{
+				AllowBareDomains: true,
+				AllowSubdomains:  true,
+				AltNames:         []string{"kubernetes"},
+				ID:               "al9qy",
+				Namespace:        "team-a",
+				Organizations:    []string{"api", "system:masters"},
+				TTL:              "8640h",
+			},
+			errorMatcher: nil,
+		},
+		{
+			name: "case 2: allowed overrides are applied",
+			config: CreateConfig{
+				AltNames: []string{"kubernetes.default"},
+				ID:       "al9qy",
+				Profile:  "api",
+			},
+			expectedConfig: CreateConfig{
+				AllowBareDomains: true,
+				AllowSubdomains:  true,
+				AltNames:         []string{"kubernetes.default"},
+				ID:               "al9qy",
+				Organizations:    []string{"api", "system:masters"},
+				TTL:              "8640h",
+			},
+			errorMatcher: nil,
+		},
+		{
+			name: "case 3: common name format and values are kept",
+			config: CreateConfig{
+				CommonNameFormat: "%s.custom.io",
+				CommonNameValues: CommonNameValues{Region: "eu"},
+				ID:               "al9qy",
+				Profile:          "api",
+			},
+			expectedConfig: CreateConfig{
+				AllowBareDomains: true,
+				AllowSubdomains:  true,
+				AltNames:         []string{"kubernetes"},
+				CommonNameFormat: "%s.custom.io",
+				CommonNameValues: CommonNameValues{Region: "eu"},
+				ID:               "al9qy",
+				Organizations:    []string{"api", "system:masters"},
+				TTL:              "8640h",
+			},
+			errorMatcher: nil,
+		},
+		{
+			name: "case 4: overrides not allowed cause invalidConfigError",
+			config: CreateConfig{
+				ID:      "al9qy",
+				Profile: "api",
+				TTL:     "1h",
+			},
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "case 5: unknown profile causes invalidConfigError",
+			config: CreateConfig{
+				ID:      "al9qy",
+				Profile: "etcd",
+			},
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "case 6: merged config is validated",
+			config: CreateConfig{
+				AltNames: []string{"foo,bar"},
+				ID:       "al9qy",
+				Profile:  "api",
+			},
+			errorMatcher: IsInvalidConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &VaultRole{
+				profiles: profiles,
+			}
+
+			config, err := r.MergeProfile(tc.config)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if !reflect.DeepEqual(config, tc.expectedConfig) {
+				t.Fatalf("CreateConfig == %#v, want %#v", config, tc.expectedConfig)
+			}
+		})
+	}
+}
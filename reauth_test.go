@@ -0,0 +1,125 @@
+package vaultrole_test
+
+import (
+	"context"
+	"testing"
+
+	vaultclient "github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/vaultrole"
+	"github.com/giantswarm/vaultrole/vaultroletest"
+)
+
+type testAuthenticator struct {
+	client *vaultclient.Client
+	logins int
+	token  string
+}
+
+func (a *testAuthenticator) Login() error {
+	a.logins++
+	a.client.SetToken(a.token)
+	return nil
+}
+
+// runningAuthenticator additionally implements vaultrole.AuthenticatorRunner
+// and reports when Run started and returned.
+type runningAuthenticator struct {
+	testAuthenticator
+
+	started chan struct{}
+	stopped chan struct{}
+}
+
+func (a *runningAuthenticator) Run(ctx context.Context) {
+	close(a.started)
+	<-ctx.Done()
+	close(a.stopped)
+}
+
+func Test_VaultRole_Reauth(t *testing.T) {
+	s := vaultroletest.NewServer()
+	defer s.Close()
+	s.Mount("", "pki-al9qy")
+	s.SetTokens("renewed")
+
+	client, err := s.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetToken("expired")
+
+	{
+		r, err := s.NewVaultRole(vaultrole.Config{VaultClient: client})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = r.Exists(vaultrole.ExistsConfig{ID: "al9qy"})
+		if !vaultrole.IsPermissionDenied(err) {
+			t.Fatalf("expected permission denied error got %#v", err)
+		}
+	}
+
+	{
+		a := &testAuthenticator{client: client, token: "renewed"}
+
+		r, err := s.NewVaultRole(vaultrole.Config{VaultClient: client, Authenticator: a})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.logins != 1 {
+			t.Fatalf("expected 1 initial login got %d", a.logins)
+		}
+
+		// The token expires after logging in initially.
+		client.SetToken("expired")
+
+		err = r.Create(vaultrole.CreateConfig{ID: "al9qy", TTL: "1h"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.logins != 2 {
+			t.Fatalf("expected 2 logins got %d", a.logins)
+		}
+	}
+}
+
+func Test_VaultRole_AuthenticatorRunner(t *testing.T) {
+	s := vaultroletest.NewServer()
+	defer s.Close()
+	s.SetTokens("renewed")
+
+	client, err := s.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetToken("expired")
+
+	a := &runningAuthenticator{
+		testAuthenticator: testAuthenticator{client: client, token: "renewed"},
+
+		started: make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	r, err := s.NewVaultRole(vaultrole.Config{VaultClient: client, Authenticator: a})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.Token() != "renewed" {
+		t.Fatalf("expected token to be set by the initial login got %q", client.Token())
+	}
+
+	<-a.started
+
+	r.Stop()
+	select {
+	case <-a.stopped:
+	default:
+		t.Fatalf("expected Run to have returned")
+	}
+
+	// Stopping again does not block.
+	r.Stop()
+}
@@ -0,0 +1,51 @@
+package renewal
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/giantswarm/microerror"
+)
+
+// writeFileAtomic writes the given data to a temporary file in the directory
+// of the given path and renames it to the path, so that readers never observe
+// partially written files.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp-")
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	tmp := f.Name()
+
+	// Clean up the temporary file in case anything fails. After renaming it
+	// the removal fails, which is fine.
+	defer os.Remove(tmp)
+
+	_, err = f.Write(data)
+	if err != nil {
+		f.Close()
+		return microerror.Mask(err)
+	}
+	err = f.Chmod(perm)
+	if err != nil {
+		f.Close()
+		return microerror.Mask(err)
+	}
+	err = f.Sync()
+	if err != nil {
+		f.Close()
+		return microerror.Mask(err)
+	}
+	err = f.Close()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = os.Rename(tmp, path)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
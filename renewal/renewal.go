@@ -0,0 +1,289 @@
+// Package renewal keeps certificates issued using VaultRole managed roles
+// valid by re-issuing them before they expire.
+package renewal
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/vaultrole"
+)
+
+// Issuer issues certificates. It is implemented by *vaultrole.VaultRole.
+type Issuer interface {
+	Issue(config vaultrole.IssueConfig) (vaultrole.Certificate, error)
+}
+
+// Certificate is a certificate issued by the Manager along with its parsed
+// leaf certificate.
+type Certificate struct {
+	vaultrole.Certificate
+
+	Leaf *x509.Certificate
+}
+
+type Config struct {
+	Issuer Issuer
+	Logger micrologger.Logger
+
+	// Issue describes the certificate to issue.
+	Issue vaultrole.IssueConfig
+
+	// CAFile, CertFile and KeyFile are optional paths the issuing CA, the
+	// certificate and the private key are written to as PEM after every
+	// issuance. Each file is replaced atomically, but not the files as a
+	// whole. The private key is written before the certificate, so that
+	// readers reacting to a new certificate find the matching key.
+	CAFile   string
+	CertFile string
+	KeyFile  string
+	// DisableJitter makes certificates renew exactly at RenewFraction of
+	// their lifetime. Jitter must be left empty when set.
+	DisableJitter bool
+	// Jitter is the maximum fraction of the certificate lifetime randomly
+	// subtracted from the renewal time, so that many managers do not renew
+	// at once. Defaults to 0.1, see DisableJitter.
+	Jitter float64
+	// RenewFraction is the fraction of the certificate lifetime after which
+	// the certificate is renewed. Defaults to 0.7.
+	RenewFraction float64
+	// RetryInterval is the time waited before retrying failed issuances.
+	// Defaults to 10 seconds.
+	RetryInterval time.Duration
+}
+
+// Manager keeps the current certificate in memory and re-issues it at a
+// fraction of its lifetime. Subscribers are notified about every new
+// certificate.
+type Manager struct {
+	issuer Issuer
+	logger micrologger.Logger
+
+	issue vaultrole.IssueConfig
+
+	caFile        string
+	certFile      string
+	keyFile       string
+	jitter        float64
+	renewFraction float64
+	retryInterval time.Duration
+
+	mutex       sync.Mutex
+	current     *Certificate
+	callbacks   []func(Certificate)
+	subscribers []chan Certificate
+}
+
+func New(config Config) (*Manager, error) {
+	if config.Issuer == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Issuer must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	if config.Issue.ID == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Issue.ID must not be empty")
+	}
+	if config.Issue.CommonName == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Issue.CommonName must not be empty")
+	}
+	if config.Jitter < 0 || config.Jitter >= 1 {
+		return nil, microerror.Maskf(invalidConfigError, "config.Jitter must be within [0, 1)")
+	}
+	if config.RenewFraction < 0 || config.RenewFraction >= 1 {
+		return nil, microerror.Maskf(invalidConfigError, "config.RenewFraction must be within [0, 1)")
+	}
+	if config.RenewFraction == 0 {
+		config.RenewFraction = 0.7
+	}
+	if config.DisableJitter && config.Jitter != 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.Jitter must be empty when config.DisableJitter is set")
+	}
+	if config.Jitter == 0 && !config.DisableJitter {
+		config.Jitter = 0.1
+	}
+	if config.Jitter > config.RenewFraction {
+		return nil, microerror.Maskf(invalidConfigError, "config.Jitter must not exceed config.RenewFraction")
+	}
+	if config.RetryInterval < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.RetryInterval must not be negative")
+	}
+	if config.RetryInterval == 0 {
+		config.RetryInterval = 10 * time.Second
+	}
+
+	m := &Manager{
+		issuer: config.Issuer,
+		logger: config.Logger,
+
+		issue: config.Issue,
+
+		caFile:        config.CAFile,
+		certFile:      config.CertFile,
+		keyFile:       config.KeyFile,
+		jitter:        config.Jitter,
+		renewFraction: config.RenewFraction,
+		retryInterval: config.RetryInterval,
+	}
+
+	return m, nil
+}
+
+// Current returns the current certificate and whether one has been issued
+// yet.
+func (m *Manager) Current() (Certificate, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.current == nil {
+		return Certificate{}, false
+	}
+
+	return *m.current, true
+}
+
+// OnRenewal registers a callback executed with every new certificate. Callbacks
+// are executed synchronously, in the order they have been registered.
+func (m *Manager) OnRenewal(fn func(Certificate)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.callbacks = append(m.callbacks, fn)
+}
+
+// Subscribe returns a channel receiving every new certificate. Subscribers
+// which do not keep up only receive the latest certificate.
+func (m *Manager) Subscribe() <-chan Certificate {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	c := make(chan Certificate, 1)
+	m.subscribers = append(m.subscribers, c)
+
+	return c
+}
+
+// Renew issues a new certificate, writes the configured files and notifies
+// subscribers.
+func (m *Manager) Renew() error {
+	issued, err := m.issuer.Issue(m.issue)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	b, _ := pem.Decode([]byte(issued.Certificate))
+	if b == nil {
+		return microerror.Maskf(invalidCertificateError, "issued certificate is not PEM encoded")
+	}
+	leaf, err := x509.ParseCertificate(b.Bytes)
+	if err != nil {
+		return microerror.Maskf(invalidCertificateError, "issued certificate cannot be parsed: %s", err.Error())
+	}
+
+	c := Certificate{
+		Certificate: issued,
+		Leaf:        leaf,
+	}
+
+	err = m.writeFiles(c)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	m.mutex.Lock()
+	m.current = &c
+	callbacks := append([]func(Certificate){}, m.callbacks...)
+	for _, s := range m.subscribers {
+		// Drop the certificate not yet received, so that the latest one can
+		// be sent without blocking.
+		select {
+		case <-s:
+		default:
+		}
+		s <- c
+	}
+	m.mutex.Unlock()
+
+	for _, fn := range callbacks {
+		fn(c)
+	}
+
+	m.logger.Log("level", "debug", "message", "issued certificate", "serialNumber", c.SerialNumber, "notAfter", leaf.NotAfter.String())
+
+	return nil
+}
+
+// Run issues a certificate in case none has been issued yet and keeps renewing
+// it until the given context is done. Failed issuances are retried.
+func (m *Manager) Run(ctx context.Context) error {
+	for {
+		var wait time.Duration
+		if c, ok := m.Current(); ok {
+			wait = time.Until(m.renewalTime(c.Leaf))
+		}
+
+		if wait > 0 {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(wait):
+			}
+		}
+
+		err := m.Renew()
+		if err != nil {
+			m.logger.Log("level", "error", "message", "failed to issue certificate", "stack", microerror.JSON(err))
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(m.retryInterval):
+			}
+		}
+	}
+}
+
+// renewalTime returns the time at which the given certificate is due to be
+// renewed, including a random jitter.
+func (m *Manager) renewalTime(leaf *x509.Certificate) time.Time {
+	lifetime := leaf.NotAfter.Sub(leaf.NotBefore)
+	fraction := m.renewFraction - rand.Float64()*m.jitter
+
+	return leaf.NotBefore.Add(time.Duration(float64(lifetime) * fraction))
+}
+
+// writeFiles writes the configured files of the given certificate. The
+// certificate is written last, see Config.CertFile.
+func (m *Manager) writeFiles(c Certificate) error {
+	files := []struct {
+		path string
+		data string
+		perm int
+	}{
+		{path: m.caFile, data: c.IssuingCA, perm: 0644},
+		{path: m.keyFile, data: c.PrivateKey, perm: 0600},
+		{path: m.certFile, data: c.Certificate.Certificate, perm: 0644},
+	}
+
+	for _, f := range files {
+		if f.path == "" {
+			continue
+		}
+
+		err := writeFileAtomic(f.path, []byte(f.data), os.FileMode(f.perm))
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
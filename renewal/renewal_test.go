@@ -0,0 +1,201 @@
+package renewal
+
+import (
+	"context"
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+
+	"github.com/giantswarm/vaultrole"
+	"github.com/giantswarm/vaultrole/vaultroletest"
+)
+
+func Test_Manager_Run(t *testing.T) {
+	s := vaultroletest.NewServer()
+	defer s.Close()
+	s.Mount("", "pki-al9qy")
+
+	r, err := s.NewVaultRole(vaultrole.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Create(vaultrole.CreateConfig{ID: "al9qy", Organizations: []string{"api"}, TTL: "1h", AllowSubdomains: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "renewal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m, err := New(Config{
+		Issuer: r,
+		Logger: microloggertest.New(),
+
+		Issue: vaultrole.IssueConfig{
+			CommonName:    "api.al9qy.g8s.gigantic.io",
+			ID:            "al9qy",
+			Organizations: []string{"api"},
+			TTL:           "2s",
+		},
+
+		CAFile:        filepath.Join(dir, "ca.pem"),
+		CertFile:      filepath.Join(dir, "crt.pem"),
+		KeyFile:       filepath.Join(dir, "key.pem"),
+		Jitter:        0.01,
+		RenewFraction: 0.5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var called int
+	m.OnRenewal(func(Certificate) { called++ })
+	w := m.Subscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- m.Run(ctx) }()
+
+	var certs []Certificate
+	for len(certs) < 2 {
+		select {
+		case c := <-w:
+			certs = append(certs, c)
+		case <-time.After(10 * time.Second):
+			t.Fatal("expected certificate to be renewed")
+		}
+	}
+
+	cancel()
+	err = <-done
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if certs[0].SerialNumber == certs[1].SerialNumber {
+		t.Fatalf("expected renewed certificate to have a new serial number, got %s twice", certs[0].SerialNumber)
+	}
+	if certs[1].Leaf.Subject.CommonName != "api.al9qy.g8s.gigantic.io" {
+		t.Fatalf("common name == %q, want %q", certs[1].Leaf.Subject.CommonName, "api.al9qy.g8s.gigantic.io")
+	}
+	if called < 2 {
+		t.Fatalf("expected callback to be called at least twice, got %d", called)
+	}
+
+	current, ok := m.Current()
+	if !ok {
+		t.Fatal("expected current certificate")
+	}
+
+	for path, expected := range map[string]string{
+		filepath.Join(dir, "ca.pem"):  current.IssuingCA,
+		filepath.Join(dir, "crt.pem"): current.Certificate.Certificate,
+		filepath.Join(dir, "key.pem"): current.PrivateKey,
+	} {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != expected {
+			t.Fatalf("%s does not contain the current certificate", path)
+		}
+	}
+
+	fi, err := os.Stat(filepath.Join(dir, "key.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Fatalf("key file mode == %o, want %o", fi.Mode().Perm(), 0600)
+	}
+}
+
+func Test_Manager_renewalTime(t *testing.T) {
+	m := &Manager{
+		jitter:        0.1,
+		renewFraction: 0.7,
+	}
+
+	notBefore := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	leaf := &x509.Certificate{
+		NotBefore: notBefore,
+		NotAfter:  notBefore.Add(100 * time.Hour),
+	}
+
+	for i := 0; i < 100; i++ {
+		r := m.renewalTime(leaf)
+		if r.Before(notBefore.Add(60*time.Hour)) || r.After(notBefore.Add(70*time.Hour)) {
+			t.Fatalf("renewal time == %s, want between 60h and 70h after %s", r, notBefore)
+		}
+	}
+}
+
+func Test_New(t *testing.T) {
+	testCases := []struct {
+		name         string
+		config       Config
+		jitter       float64
+		errorMatcher func(error) bool
+	}{
+		{
+			name:         "case 0: missing issuer",
+			config:       Config{Logger: microloggertest.New(), Issue: vaultrole.IssueConfig{ID: "al9qy", CommonName: "foo"}},
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name:         "case 1: missing common name",
+			config:       Config{Issuer: &vaultrole.VaultRole{}, Logger: microloggertest.New(), Issue: vaultrole.IssueConfig{ID: "al9qy"}},
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name:         "case 2: jitter exceeding renew fraction",
+			config:       Config{Issuer: &vaultrole.VaultRole{}, Logger: microloggertest.New(), Issue: vaultrole.IssueConfig{ID: "al9qy", CommonName: "foo"}, Jitter: 0.5, RenewFraction: 0.3},
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name:   "case 3: defaults",
+			config: Config{Issuer: &vaultrole.VaultRole{}, Logger: microloggertest.New(), Issue: vaultrole.IssueConfig{ID: "al9qy", CommonName: "foo"}},
+			jitter: 0.1,
+		},
+		{
+			name:   "case 4: jitter disabled",
+			config: Config{Issuer: &vaultrole.VaultRole{}, Logger: microloggertest.New(), Issue: vaultrole.IssueConfig{ID: "al9qy", CommonName: "foo"}, DisableJitter: true},
+			jitter: 0,
+		},
+		{
+			name:         "case 5: jitter disabled and set",
+			config:       Config{Issuer: &vaultrole.VaultRole{}, Logger: microloggertest.New(), Issue: vaultrole.IssueConfig{ID: "al9qy", CommonName: "foo"}, DisableJitter: true, Jitter: 0.2},
+			errorMatcher: IsInvalidConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := New(tc.config)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if err == nil && m.jitter != tc.jitter {
+				t.Fatalf("jitter == %v, want %v", m.jitter, tc.jitter)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package vaultrole
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/vaultrole/key"
+)
+
+// ListCertificates returns the certificates issued for the given cluster ID,
+// including revoked and expired ones. Each certificate is attributed to the
+// role matching its organizations. The certificate of the CA is not returned.
+func (r *VaultRole) ListCertificates(config ListCertificatesConfig) ([]IssuedCertificate, error) {
+	if config.ID == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.ID must not be empty")
+	}
+
+	var secret *api.Secret
+	err := r.withReauth(func() error {
+		var err error
+		secret, err = r.logicalClient.List(r.ctx, config.Namespace, key.ListCertsPath(config.ID))
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		return nil
+	})
+	if IsNoVaultHandlerDefined(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	// In case there is not a single certificate, secret is nil.
+	if secret == nil {
+		return nil, nil
+	}
+
+	var serials []string
+	if list, ok := secret.Data["keys"].([]interface{}); ok {
+		for _, k := range list {
+			if s, ok := k.(string); ok {
+				serials = append(serials, s)
+			}
+		}
+	}
+
+	var certs []IssuedCertificate
+	for _, s := range serials {
+		c, err := r.readCertificate(config.Namespace, config.ID, s)
+		if IsNotFound(err) {
+			// The certificate got removed after listing it, e.g. by tidying
+			// the PKI backend.
+			continue
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if c.Certificate.IsCA {
+			continue
+		}
+
+		certs = append(certs, c)
+	}
+
+	return certs, nil
+}
+
+// Revoke revokes the certificate of the given serial number and returns the
+// time it got revoked at. Revoking a revoked certificate returns the original
+// revocation time. In dry run mode the certificate is not revoked and the
+// zero time is returned.
+func (r *VaultRole) Revoke(config RevokeConfig) (time.Time, error) {
+	if config.ID == "" {
+		return time.Time{}, microerror.Maskf(invalidConfigError, "config.ID must not be empty")
+	}
+	if config.SerialNumber == "" {
+		return time.Time{}, microerror.Maskf(invalidConfigError, "config.SerialNumber must not be empty")
+	}
+
+	p := key.RevokePath(config.ID)
+
+	if r.dryRun {
+		r.logger.Log("level", "info", "message", "dry run, skipping revocation of certificate", "namespace", config.Namespace, "path", p, "serialNumber", config.SerialNumber)
+		return time.Time{}, nil
+	}
+
+	var secret *api.Secret
+	err := r.withReauth(func() error {
+		var err error
+		secret, err = r.logicalClient.Write(r.ctx, config.Namespace, p, map[string]interface{}{
+			"serial_number": config.SerialNumber,
+		})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, microerror.Mask(err)
+	}
+
+	if secret == nil {
+		return time.Time{}, microerror.Maskf(invalidVaultResponseError, "no revocation time returned at path '%s'", p)
+	}
+
+	t, err := parseUnixTime("revocation_time", secret.Data["revocation_time"])
+	if err != nil {
+		return time.Time{}, microerror.Mask(err)
+	}
+
+	r.logger.Log("level", "debug", "message", "revoked certificate", "namespace", config.Namespace, "path", p, "serialNumber", config.SerialNumber)
+
+	return t, nil
+}
+
+// RevokeRole revokes every certificate issued using the role of the given
+// cluster ID and organizations, e.g. after the organizations got removed from
+// RBAC. Revoked and expired certificates are skipped. The returned results
+// list every certificate RevokeRole tried to revoke. In case any revocation
+// failed, an error is returned in addition to the results, which then carry
+// the per item errors. In dry run mode the certificates are only reported.
+func (r *VaultRole) RevokeRole(config RevokeRoleConfig) ([]RevokeResult, error) {
+	certs, err := r.ListCertificates(ListCertificatesConfig{
+		ID:        config.ID,
+		Namespace: config.Namespace,
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	// The organizations are copied since computing the role name sorts them in
+	// place.
+	name := key.RoleName(config.ID, append([]string(nil), config.Organizations...))
+	now := time.Now()
+
+	var results []RevokeResult
+	for _, c := range certs {
+		if c.RoleName != name || !c.RevocationTime.IsZero() || c.Certificate.NotAfter.Before(now) {
+			continue
+		}
+
+		t, err := r.Revoke(RevokeConfig{
+			ID:           config.ID,
+			Namespace:    config.Namespace,
+			SerialNumber: c.SerialNumber,
+		})
+
+		results = append(results, RevokeResult{
+			SerialNumber:   c.SerialNumber,
+			RevocationTime: t,
+			Error:          err,
+		})
+	}
+
+	// Aggregate the errors of all failed revocations.
+	{
+		var failed int
+		for _, res := range results {
+			if res.Error != nil {
+				failed++
+			}
+		}
+
+		if failed > 0 {
+			return results, microerror.Maskf(executionFailedError, "%d of %d certificates failed to be revoked", failed, len(results))
+		}
+	}
+
+	return results, nil
+}
+
+func (r *VaultRole) readCertificate(namespace string, ID string, serialNumber string) (IssuedCertificate, error) {
+	p := key.CertPath(ID, serialNumber)
+
+	var secret *api.Secret
+	err := r.withReauth(func() error {
+		var err error
+		secret, err = r.logicalClient.Read(r.ctx, namespace, p)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return IssuedCertificate{}, microerror.Mask(err)
+	}
+
+	if secret == nil {
+		return IssuedCertificate{}, microerror.Maskf(notFoundError, "certificate at path '%s'", p)
+	}
+
+	s, ok := secret.Data["certificate"].(string)
+	if !ok {
+		return IssuedCertificate{}, microerror.Maskf(invalidVaultResponseError, "Vault secret.Data[\"certificate\"] type is %T, expected string", secret.Data["certificate"])
+	}
+	b, _ := pem.Decode([]byte(s))
+	if b == nil {
+		return IssuedCertificate{}, microerror.Maskf(invalidVaultResponseError, "certificate at path '%s' is not PEM encoded", p)
+	}
+	cert, err := x509.ParseCertificate(b.Bytes)
+	if err != nil {
+		return IssuedCertificate{}, microerror.Maskf(invalidVaultResponseError, "certificate at path '%s' cannot be parsed: %s", p, err.Error())
+	}
+
+	var revocationTime time.Time
+	if v, ok := secret.Data["revocation_time"]; ok {
+		revocationTime, err = parseUnixTime("revocation_time", v)
+		if err != nil {
+			return IssuedCertificate{}, microerror.Mask(err)
+		}
+	}
+
+	c := IssuedCertificate{
+		Certificate:    cert,
+		RoleName:       key.RoleName(ID, append([]string(nil), cert.Subject.Organization...)),
+		RevocationTime: revocationTime,
+		SerialNumber:   strings.Replace(serialNumber, "-", ":", -1),
+	}
+
+	return c, nil
+}
+
+// parseUnixTime parses the unix timestamp returned by Vault in the given
+// field. Vault returns 0 for unset timestamps, which is parsed into the zero
+// time.
+func parseUnixTime(field string, v interface{}) (time.Time, error) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return time.Time{}, microerror.Maskf(invalidVaultResponseError, "Vault secret.Data[%q] type is %T, expected %T", field, v, n)
+	}
+	i, err := n.Int64()
+	if err != nil {
+		return time.Time{}, microerror.Maskf(invalidVaultResponseError, "Vault secret.Data[%q] must be a unix timestamp: %s", field, err.Error())
+	}
+
+	if i == 0 {
+		return time.Time{}, nil
+	}
+
+	return time.Unix(i, 0), nil
+}
@@ -0,0 +1,209 @@
+package vaultrole_test
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	vaultclient "github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/vaultrole"
+	"github.com/giantswarm/vaultrole/key"
+	"github.com/giantswarm/vaultrole/middleware"
+	"github.com/giantswarm/vaultrole/vaultroletest"
+)
+
+func Test_VaultRole_RevokeRole(t *testing.T) {
+	s := vaultroletest.NewServer()
+	defer s.Close()
+	s.Mount("", "pki-al9qy")
+
+	newVaultRole := func(dryRun bool) *vaultrole.VaultRole {
+		r, err := s.NewVaultRole(vaultrole.Config{DryRun: dryRun})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return r
+	}
+	r := newVaultRole(false)
+
+	issued := map[string][]string{}
+	for _, orgs := range [][]string{{"api"}, {"api"}, {"etcd"}} {
+		err := r.Create(vaultrole.CreateConfig{ID: "al9qy", Organizations: orgs, TTL: "1h", AllowSubdomains: true})
+		if err != nil && !vaultrole.IsAlreadyExists(err) {
+			t.Fatal(err)
+		}
+
+		c, err := r.Issue(vaultrole.IssueConfig{CommonName: "x.al9qy.g8s.gigantic.io", ID: "al9qy", Organizations: orgs})
+		if err != nil {
+			t.Fatal(err)
+		}
+		issued[orgs[0]] = append(issued[orgs[0]], c.SerialNumber)
+	}
+	sort.Strings(issued["api"])
+
+	{
+		certs, err := r.ListCertificates(vaultrole.ListCertificatesConfig{ID: "al9qy"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(certs) != 3 {
+			t.Fatalf("expected 3 certificates without the CA, got %d", len(certs))
+		}
+		for _, c := range certs {
+			if c.RoleName != key.RoleName("al9qy", c.Certificate.Subject.Organization) {
+				t.Fatalf("role name == %q, want role of organizations %q", c.RoleName, c.Certificate.Subject.Organization)
+			}
+			if !c.RevocationTime.IsZero() {
+				t.Fatalf("expected certificate %s not to be revoked", c.SerialNumber)
+			}
+		}
+	}
+
+	// In dry run mode the certificates are reported but not revoked.
+	{
+		results, err := newVaultRole(true).RevokeRole(vaultrole.RevokeRoleConfig{ID: "al9qy", Organizations: []string{"api"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 2 {
+			t.Fatalf("expected 2 results, got %d", len(results))
+		}
+		for _, res := range results {
+			if !res.RevocationTime.IsZero() {
+				t.Fatalf("expected certificate %s not to be revoked in dry run mode", res.SerialNumber)
+			}
+		}
+	}
+
+	{
+		results, err := r.RevokeRole(vaultrole.RevokeRoleConfig{ID: "al9qy", Organizations: []string{"api"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var serials []string
+		for _, res := range results {
+			if res.RevocationTime.IsZero() {
+				t.Fatalf("expected certificate %s to be revoked", res.SerialNumber)
+			}
+			serials = append(serials, res.SerialNumber)
+		}
+		sort.Strings(serials)
+		if len(serials) != 2 || serials[0] != issued["api"][0] || serials[1] != issued["api"][1] {
+			t.Fatalf("revoked == %q, want %q", serials, issued["api"])
+		}
+	}
+
+	{
+		certs, err := r.ListCertificates(vaultrole.ListCertificatesConfig{ID: "al9qy"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range certs {
+			revoked := !c.RevocationTime.IsZero()
+			if revoked != (c.SerialNumber != issued["etcd"][0]) {
+				t.Fatalf("certificate %s revoked == %t, want %t", c.SerialNumber, revoked, !revoked)
+			}
+		}
+	}
+
+	// Revoked certificates are skipped.
+	{
+		results, err := r.RevokeRole(vaultrole.RevokeRoleConfig{ID: "al9qy", Organizations: []string{"api"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 0 {
+			t.Fatalf("expected no results, got %d", len(results))
+		}
+	}
+
+	_, err := r.Revoke(vaultrole.RevokeConfig{ID: "al9qy", SerialNumber: "00:11"})
+	if err == nil {
+		t.Fatal("expected revoking an unknown certificate to fail")
+	}
+}
+
+func Test_VaultRole_ListCertificates_NoCertificates(t *testing.T) {
+	s := vaultroletest.NewServer()
+	defer s.Close()
+	s.Mount("", "pki-al9qy")
+
+	r, err := s.NewVaultRole(vaultrole.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ID := range []string{"al9qy", "5xchu"} {
+		certs, err := r.ListCertificates(vaultrole.ListCertificatesConfig{ID: ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(certs) != 0 {
+			t.Fatalf("expected no certificates for %s, got %d", ID, len(certs))
+		}
+	}
+}
+
+func Test_VaultRole_RevokeRole_Removed(t *testing.T) {
+	s := vaultroletest.NewServer()
+	defer s.Close()
+	s.Mount("", "pki-al9qy")
+
+	client, err := s.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reading the removed certificate returns no secret, as Vault does for
+	// certificates removed after listing them.
+	var removed string
+	remove := middleware.Func(func(ctx context.Context, req middleware.Request, next middleware.Handler) (*vaultclient.Secret, error) {
+		if removed != "" && req.Operation == middleware.OperationRead && req.Path == key.CertPath("al9qy", strings.Replace(removed, ":", "-", -1)) {
+			return nil, nil
+		}
+		return next(ctx, req)
+	})
+
+	r, err := s.NewVaultRole(vaultrole.Config{
+		LogicalClient: vaultrole.ChainLogicalClient(vaultrole.NewLogicalClient(client), remove),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	orgs := []string{"system:masters", "api"}
+	err = r.Create(vaultrole.CreateConfig{ID: "al9qy", Organizations: orgs, TTL: "1h", AllowSubdomains: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var serials []string
+	for i := 0; i < 2; i++ {
+		c, err := r.Issue(vaultrole.IssueConfig{CommonName: "x.al9qy.g8s.gigantic.io", ID: "al9qy", Organizations: orgs})
+		if err != nil {
+			t.Fatal(err)
+		}
+		serials = append(serials, c.SerialNumber)
+	}
+	removed = serials[0]
+
+	orgs = []string{"system:masters", "api"}
+	results, err := r.RevokeRole(vaultrole.RevokeRoleConfig{ID: "al9qy", Organizations: orgs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].SerialNumber != serials[1] {
+		t.Fatalf("expected only certificate %s to be revoked got %#v", serials[1], results)
+	}
+
+	// The organizations of the config are left in their order, since they
+	// might be shared with the caller.
+	if !reflect.DeepEqual(orgs, []string{"system:masters", "api"}) {
+		t.Fatalf("organizations == %q, want %q", orgs, []string{"system:masters", "api"})
+	}
+}
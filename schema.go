@@ -0,0 +1,296 @@
+package vaultrole
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/hashicorp/vault/sdk/helper/parseutil"
+)
+
+const (
+	fieldTypeBool fieldType = iota
+	fieldTypeDuration
+	fieldTypeStringList
+)
+
+// fieldType is the Go type a role field is decoded into. Bool fields decode
+// into bool, duration fields into time.Duration and string list fields into
+// []string.
+type fieldType int
+
+func (t fieldType) String() string {
+	switch t {
+	case fieldTypeBool:
+		return "bool"
+	case fieldTypeDuration:
+		return "time.Duration"
+	case fieldTypeStringList:
+		return "[]string"
+	default:
+		return fmt.Sprintf("fieldType(%d)", int(t))
+	}
+}
+
+// roleField describes a field of Vault PKI roles managed by VaultRole.
+type roleField struct {
+	// Name is the name of the field in Vault requests and responses.
+	Name string
+	Type fieldType
+	// Required defines whether the field must be present in Vault responses.
+	// Decoding responses lacking required fields fails.
+	Required bool
+	// Default is decoded in case an optional field is missing from a Vault
+	// response. It is given in any shape accepted for responses. Optional
+	// fields without default decode into the zero value of their type.
+	Default interface{}
+	// ListOrString defines whether a string list field is also accepted as
+	// comma separated string. Such fields are encoded as comma separated
+	// string, which is understood by all Vault versions.
+	ListOrString bool
+	// Payload returns the value encoded for the field from the given payload.
+	Payload func(p WritePayload) interface{}
+	// SetRole stores the given decoded value of the field in the given role.
+	SetRole func(role *Role, v interface{})
+}
+
+type schema []roleField
+
+// roleSchema defines how the fields of Vault PKI roles are encoded from
+// WritePayload and decoded into Role. Vault changed the representation of
+// roles over time, so decoding tolerates the following response shapes.
+//
+//	Vault            allowed_domains, organization    ttl
+//	older 0.x        comma separated string           duration string, e.g. "720h"
+//	later 0.x, 1.x   list of strings                  number of seconds
+//
+// Numbers are decoded by the Vault client as json.Number. Integers and floats
+// are accepted as well for responses decoded differently, and strings holding
+// a number of seconds are accepted for the TTL.
+var roleSchema = schema{
+	{
+		Name:     "allow_bare_domains",
+		Type:     fieldTypeBool,
+		Required: true,
+		Payload:  func(p WritePayload) interface{} { return p.AllowBareDomains },
+		SetRole:  func(role *Role, v interface{}) { role.AllowBareDomains = v.(bool) },
+	},
+	{
+		Name:     "allow_subdomains",
+		Type:     fieldTypeBool,
+		Required: true,
+		Payload:  func(p WritePayload) interface{} { return p.AllowSubdomains },
+		SetRole:  func(role *Role, v interface{}) { role.AllowSubdomains = v.(bool) },
+	},
+	{
+		Name:         "allowed_domains",
+		Type:         fieldTypeStringList,
+		Required:     true,
+		ListOrString: true,
+		Payload:      func(p WritePayload) interface{} { return p.AllowedDomains },
+		// The first allowed domain is the common name as computed by
+		// key.AllowedDomains. The remaining ones are the alternative names.
+		SetRole: func(role *Role, v interface{}) {
+			allowedDomains := v.([]string)
+			if len(allowedDomains) > 0 {
+				role.CommonName = allowedDomains[0]
+				role.AltNames = allowedDomains[1:]
+			} else {
+				role.AltNames = allowedDomains
+			}
+		},
+	},
+	{
+		Name:         "organization",
+		Type:         fieldTypeStringList,
+		Required:     true,
+		ListOrString: true,
+		Payload:      func(p WritePayload) interface{} { return p.Organization },
+		SetRole:      func(role *Role, v interface{}) { role.Organizations = v.([]string) },
+	},
+	{
+		Name:     "ttl",
+		Type:     fieldTypeDuration,
+		Required: true,
+		Payload:  func(p WritePayload) interface{} { return p.TTL },
+		SetRole:  func(role *Role, v interface{}) { role.TTL = v.(time.Duration) },
+	},
+}
+
+// has returns whether the schema defines a field with the given name.
+func (s schema) has(name string) bool {
+	for _, f := range s {
+		if f.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// decode returns the role holding the fields of the schema found in the given
+// Vault response data. Fields not part of the schema are ignored. Missing
+// required fields cause an invalidVaultResponseError, missing optional fields
+// decode their default.
+func (s schema) decode(data map[string]interface{}) (Role, error) {
+	var role Role
+
+	for _, f := range s {
+		v, exists := data[f.Name]
+		if !exists && f.Required {
+			return Role{}, microerror.Maskf(invalidVaultResponseError, "%s missing from Vault api.Secret.Data", f.Name)
+		}
+		if !exists {
+			if f.Default == nil {
+				continue
+			}
+			v = f.Default
+		}
+
+		var d interface{}
+		var err error
+		switch f.Type {
+		case fieldTypeBool:
+			d, err = decodeBool(f, v)
+		case fieldTypeDuration:
+			d, err = decodeDuration(f, v)
+		case fieldTypeStringList:
+			d, err = decodeStringList(f, v)
+		}
+		if err != nil {
+			return Role{}, microerror.Mask(err)
+		}
+
+		f.SetRole(&role, d)
+	}
+
+	return role, nil
+}
+
+// encode returns the data written to Vault for the given payload. Bool values
+// must be bool, duration values either time.Duration or string and string
+// list values either []string or string.
+func (s schema) encode(p WritePayload) (map[string]interface{}, error) {
+	data := map[string]interface{}{}
+
+	for _, f := range s {
+		v := f.Payload(p)
+
+		switch f.Type {
+		case fieldTypeBool:
+			b, ok := v.(bool)
+			if !ok {
+				return nil, microerror.Maskf(invalidConfigError, "value of field %s is %T, expected %s", f.Name, v, f.Type)
+			}
+			data[f.Name] = b
+
+		case fieldTypeDuration:
+			switch v := v.(type) {
+			case string:
+				data[f.Name] = v
+			case time.Duration:
+				data[f.Name] = fmt.Sprintf("%ds", int64(v/time.Second))
+			default:
+				return nil, microerror.Maskf(invalidConfigError, "value of field %s is %T, expected %s", f.Name, v, f.Type)
+			}
+
+		case fieldTypeStringList:
+			var list []string
+			switch v := v.(type) {
+			case string:
+				if !f.ListOrString {
+					return nil, microerror.Maskf(invalidConfigError, "value of field %s is %T, expected %s", f.Name, v, f.Type)
+				}
+				data[f.Name] = v
+				continue
+			case []string:
+				list = v
+			default:
+				return nil, microerror.Maskf(invalidConfigError, "value of field %s is %T, expected %s", f.Name, v, f.Type)
+			}
+
+			if f.ListOrString {
+				data[f.Name] = strings.Join(list, ",")
+			} else {
+				data[f.Name] = list
+			}
+		}
+	}
+
+	return data, nil
+}
+
+func decodeBool(f roleField, v interface{}) (bool, error) {
+	b, ok := v.(bool)
+	if !ok {
+		return false, microerror.Maskf(invalidVaultResponseError, "Vault secret.Data[%q] type is %T, expected %s", f.Name, v, f.Type)
+	}
+
+	return b, nil
+}
+
+func decodeDuration(f roleField, v interface{}) (time.Duration, error) {
+	var s string
+	switch v := v.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = v
+	case int:
+		return time.Duration(v) * time.Second, nil
+	case int64:
+		return time.Duration(v) * time.Second, nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) || math.Abs(v) > math.MaxInt64/float64(time.Second) {
+			return 0, microerror.Maskf(invalidVaultResponseError, "Vault secret.Data[%q] value %v is out of range", f.Name, v)
+		}
+		return time.Duration(v * float64(time.Second)), nil
+	default:
+		return 0, microerror.Maskf(invalidVaultResponseError, "Vault secret.Data[%q] type is %T, expected json.Number, number or string", f.Name, v)
+	}
+
+	if s == "" {
+		return 0, nil
+	}
+
+	d, err := parseutil.ParseDurationSecond(s)
+	if err != nil {
+		return 0, microerror.Maskf(invalidVaultResponseError, "Vault secret.Data[%q] value %q is not a valid duration: %s", f.Name, s, err.Error())
+	}
+
+	return d, nil
+}
+
+// decodeStringList decodes the given list. An empty string results in a nil
+// list, so that roles stored by older Vault versions decode the same way as
+// before.
+func decodeStringList(f roleField, v interface{}) ([]string, error) {
+	switch v := v.(type) {
+	case string:
+		if !f.ListOrString {
+			return nil, microerror.Maskf(invalidVaultResponseError, "Vault secret.Data[%q] type is %T, expected %s", f.Name, v, f.Type)
+		}
+		if v == "" {
+			return nil, nil
+		}
+		return strings.Split(v, ","), nil
+	case []string:
+		return v, nil
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for i, val := range v {
+			s, ok := val.(string)
+			if !ok {
+				return nil, microerror.Maskf(invalidVaultResponseError, "Vault secret.Data[%q][%d] has unexpected type '%T'. It's not string nor []string.", f.Name, i, val)
+			}
+			list = append(list, s)
+		}
+
+		return list, nil
+	default:
+		return nil, microerror.Maskf(invalidVaultResponseError, "Vault secret.Data[%q] type is '%T'. It's not string, []string nor []interface{} (masking strings).", f.Name, v)
+	}
+}
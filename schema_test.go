@@ -0,0 +1,255 @@
+package vaultrole
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// Test_roleSchema_compatibility decodes role responses as returned by
+// different Vault versions.
+func Test_roleSchema_compatibility(t *testing.T) {
+	expectedRole := Role{
+		AllowSubdomains: true,
+		AltNames:        []string{"api.internal"},
+		CommonName:      "al9qy.g8s.gigantic.io",
+		Organizations:   []string{"api", "system:masters"},
+		TTL:             720 * time.Hour,
+	}
+
+	testCases := []struct {
+		name         string
+		response     string
+		expectedRole Role
+	}{
+		{
+			name: "case 0: older Vault 0.x with comma separated strings and duration string",
+			response: `{
+				"allow_bare_domains": false,
+				"allow_subdomains": true,
+				"allowed_domains": "al9qy.g8s.gigantic.io,api.internal",
+				"organization": "api,system:masters",
+				"ttl": "720h"
+			}`,
+			expectedRole: expectedRole,
+		},
+		{
+			name: "case 1: later Vault 0.x with lists and seconds",
+			response: `{
+				"allow_bare_domains": false,
+				"allow_subdomains": true,
+				"allowed_domains": ["al9qy.g8s.gigantic.io", "api.internal"],
+				"organization": ["api", "system:masters"],
+				"ttl": 2592000
+			}`,
+			expectedRole: expectedRole,
+		},
+		{
+			name: "case 2: Vault 1.x with lists, seconds and additional fields",
+			response: `{
+				"allow_bare_domains": false,
+				"allow_subdomains": true,
+				"allowed_domains": ["al9qy.g8s.gigantic.io", "api.internal"],
+				"key_type": "rsa",
+				"organization": ["api", "system:masters"],
+				"ttl": 2592000
+			}`,
+			expectedRole: func() Role {
+				r := expectedRole
+				r.Extra = map[string]interface{}{"key_type": "rsa"}
+				return r
+			}(),
+		},
+		{
+			name: "case 3: ttl as string holding seconds",
+			response: `{
+				"allow_bare_domains": false,
+				"allow_subdomains": true,
+				"allowed_domains": ["al9qy.g8s.gigantic.io", "api.internal"],
+				"organization": ["api", "system:masters"],
+				"ttl": "2592000"
+			}`,
+			expectedRole: expectedRole,
+		},
+		{
+			name: "case 4: unset ttl of older Vault 0.x",
+			response: `{
+				"allow_bare_domains": false,
+				"allow_subdomains": true,
+				"allowed_domains": "al9qy.g8s.gigantic.io,api.internal",
+				"organization": "api,system:masters",
+				"ttl": ""
+			}`,
+			expectedRole: func() Role {
+				r := expectedRole
+				r.TTL = 0
+				return r
+			}(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var data map[string]interface{}
+			d := json.NewDecoder(strings.NewReader(tc.response))
+			d.UseNumber()
+			err := d.Decode(&data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			role, err := vaultSecretToRole(&api.Secret{Data: data})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if !reflect.DeepEqual(role, tc.expectedRole) {
+				t.Fatalf("Role == %#v, want %#v", role, tc.expectedRole)
+			}
+		})
+	}
+}
+
+func Test_roleSchema_decodeTTL(t *testing.T) {
+	for _, v := range []interface{}{3600, int64(3600), float64(3600), json.Number("3600"), "3600", "3600s", "1h"} {
+		role, err := roleSchema.decode(map[string]interface{}{
+			"allow_bare_domains": false,
+			"allow_subdomains":   false,
+			"allowed_domains":    []interface{}{},
+			"organization":       []interface{}{},
+			"ttl":                v,
+		})
+		if err != nil {
+			t.Fatalf("%#v: error == %#v, want nil", v, err)
+		}
+		if role.TTL != time.Hour {
+			t.Fatalf("%#v: TTL == %v, want %v", v, role.TTL, time.Hour)
+		}
+	}
+}
+
+func Test_roleSchema_decodeMissing(t *testing.T) {
+	_, err := roleSchema.decode(map[string]interface{}{
+		"allow_bare_domains": false,
+		"allow_subdomains":   false,
+		"allowed_domains":    []interface{}{},
+		"organization":       []interface{}{},
+	})
+	if !IsInvalidVaultResponse(err) {
+		t.Fatalf("expected invalid vault response error got %#v", err)
+	}
+}
+
+func Test_schema_decodeOptional(t *testing.T) {
+	s := schema{
+		{
+			Name:     "allow_bare_domains",
+			Type:     fieldTypeBool,
+			Required: true,
+			SetRole:  func(role *Role, v interface{}) { role.AllowBareDomains = v.(bool) },
+		},
+		{
+			Name:    "allow_subdomains",
+			Type:    fieldTypeBool,
+			SetRole: func(role *Role, v interface{}) { role.AllowSubdomains = v.(bool) },
+		},
+		{
+			Name:    "ttl",
+			Type:    fieldTypeDuration,
+			Default: "720h",
+			SetRole: func(role *Role, v interface{}) { role.TTL = v.(time.Duration) },
+		},
+	}
+
+	testCases := []struct {
+		name         string
+		data         map[string]interface{}
+		expectedRole Role
+		errorMatcher func(error) bool
+	}{
+		{
+			name: "case 0: present fields are decoded",
+			data: map[string]interface{}{
+				"allow_bare_domains": true,
+				"allow_subdomains":   true,
+				"ttl":                json.Number("3600"),
+			},
+			expectedRole: Role{AllowBareDomains: true, AllowSubdomains: true, TTL: time.Hour},
+		},
+		{
+			name: "case 1: missing optional fields decode their default",
+			data: map[string]interface{}{
+				"allow_bare_domains": true,
+			},
+			expectedRole: Role{AllowBareDomains: true, TTL: 720 * time.Hour},
+		},
+		{
+			name: "case 2: missing required field causes invalidVaultResponseError",
+			data: map[string]interface{}{
+				"allow_subdomains": true,
+				"ttl":              json.Number("3600"),
+			},
+			errorMatcher: IsInvalidVaultResponse,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			role, err := s.decode(tc.data)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if !reflect.DeepEqual(role, tc.expectedRole) {
+				t.Fatalf("Role == %#v, want %#v", role, tc.expectedRole)
+			}
+		})
+	}
+}
+
+func Test_roleSchema_encode(t *testing.T) {
+	data, err := roleSchema.encode(WritePayload{
+		AllowSubdomains: true,
+		AllowedDomains:  "al9qy.g8s.gigantic.io,api.internal",
+		Organization:    "api",
+		TTL:             "1h",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"allow_bare_domains": false,
+		"allow_subdomains":   true,
+		"allowed_domains":    "al9qy.g8s.gigantic.io,api.internal",
+		"organization":       "api",
+		"ttl":                "1h",
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Fatalf("data == %#v, want %#v", data, expected)
+	}
+
+	s := schema{
+		{
+			Name:    "ttl",
+			Type:    fieldTypeDuration,
+			Payload: func(p WritePayload) interface{} { return 3600 },
+		},
+	}
+	_, err = s.encode(WritePayload{})
+	if !IsInvalidConfig(err) {
+		t.Fatalf("expected invalid config error got %#v", err)
+	}
+}
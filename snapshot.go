@@ -0,0 +1,269 @@
+package vaultrole
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/vaultrole/key"
+)
+
+// SnapshotVersion is the version of the snapshot format produced by Export.
+const SnapshotVersion = 1
+
+const (
+	// ImportConflictFail makes Import fail without writing anything in case
+	// any role of the snapshot already exists.
+	ImportConflictFail ImportConflictPolicy = "fail"
+	// ImportConflictOverwrite makes Import overwrite existing roles.
+	ImportConflictOverwrite ImportConflictPolicy = "overwrite"
+	// ImportConflictSkip makes Import leave existing roles untouched.
+	ImportConflictSkip ImportConflictPolicy = "skip"
+)
+
+// ImportConflictPolicy defines how Import treats roles which already exist.
+type ImportConflictPolicy string
+
+// Snapshot holds every role of the PKI backend of a cluster, as produced by
+// Export. Snapshots can be serialised as JSON.
+type Snapshot struct {
+	Version   int         `json:"version"`
+	ClusterID string      `json:"clusterID"`
+	Namespace string      `json:"namespace,omitempty"`
+	Roles     []NamedRole `json:"roles"`
+}
+
+type ExportConfig struct {
+	ID        string
+	Namespace string
+}
+
+type ImportConfig struct {
+	// ConflictPolicy defines how roles which already exist are treated.
+	// Defaults to ImportConflictFail.
+	ConflictPolicy ImportConflictPolicy
+	// ID is the cluster ID the roles are imported into. Defaults to the
+	// cluster ID of the snapshot.
+	ID string
+	// Namespace is the namespace the roles are imported into. Defaults to the
+	// namespace of the snapshot.
+	Namespace string
+	Snapshot  Snapshot
+}
+
+// ImportResult describes the outcome of importing a single role.
+type ImportResult struct {
+	Name string
+	// Action is one of create, overwrite or skip.
+	Action string
+}
+
+// Export returns a snapshot of every role of the PKI backend of the given
+// cluster.
+func (r *VaultRole) Export(config ExportConfig) (Snapshot, error) {
+	roles, err := r.List(ListConfig(config))
+	if err != nil {
+		return Snapshot{}, microerror.Mask(err)
+	}
+
+	s := Snapshot{
+		Version:   SnapshotVersion,
+		ClusterID: config.ID,
+		Namespace: config.Namespace,
+		Roles:     roles,
+	}
+
+	return s, nil
+}
+
+// Import recreates the roles of the given snapshot. Roles keep their names,
+// except for roles named after the organizations they hold, which are renamed
+// according to the target cluster ID. Common names and extra fields are
+// restored from the snapshot, where the cluster ID of the snapshot is replaced
+// by the target cluster ID within common names. Roles of snapshots lacking the
+// common name get it computed like Create does. After writing, every imported
+// role is read back and verified to match the snapshot, including its common
+// name and extra fields.
+func (r *VaultRole) Import(config ImportConfig) ([]ImportResult, error) {
+	if config.Snapshot.Version != SnapshotVersion {
+		return nil, microerror.Maskf(invalidConfigError, "snapshot version %d is not supported, expected %d", config.Snapshot.Version, SnapshotVersion)
+	}
+	if config.ConflictPolicy == "" {
+		config.ConflictPolicy = ImportConflictFail
+	}
+	switch config.ConflictPolicy {
+	case ImportConflictFail, ImportConflictOverwrite, ImportConflictSkip:
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "config.ConflictPolicy %q is not supported", config.ConflictPolicy)
+	}
+	if config.ID == "" {
+		config.ID = config.Snapshot.ClusterID
+	}
+	if config.Namespace == "" {
+		config.Namespace = config.Snapshot.Namespace
+	}
+
+	// Fetch the existing roles of the target cluster in order to detect
+	// conflicts and record the previous roles.
+	existing := map[string]Role{}
+	{
+		roles, err := r.List(ListConfig{ID: config.ID, Namespace: config.Namespace})
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		for _, n := range roles {
+			existing[n.Name] = n.Role
+		}
+	}
+
+	// Plan all writes before writing anything, so that invalid roles and
+	// conflicts cause Import to fail without partially importing the snapshot.
+	var results []ImportResult
+	var requests []WriteRequest
+	var configs []writeConfig
+	{
+		for _, n := range config.Snapshot.Roles {
+			c := writeConfig{
+				AllowBareDomains: n.Role.AllowBareDomains,
+				AllowSubdomains:  n.Role.AllowSubdomains,
+				AltNames:         n.Role.AltNames,
+				ID:               config.ID,
+				Namespace:        config.Namespace,
+				Organizations:    append([]string(nil), n.Role.Organizations...),
+				TTL:              n.Role.TTL.String(),
+			}
+
+			req, err := r.Plan(PlanConfig(c))
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			if commonName := importCommonName(n.Role, config.Snapshot.ClusterID, config.ID); commonName != "" {
+				req.Payload.AllowedDomains = key.JoinAllowedDomains(commonName, n.Role.AltNames)
+			}
+			req.Payload.Extra = n.Role.Extra
+
+			name := n.Name
+			if name == key.RoleName(config.Snapshot.ClusterID, append([]string(nil), n.Role.Organizations...)) {
+				name = key.RoleName(config.ID, append([]string(nil), n.Role.Organizations...))
+			}
+			req.Path = key.NamedRolePath(config.ID, name)
+
+			res := ImportResult{
+				Name:   name,
+				Action: "create",
+			}
+			if _, ok := existing[name]; ok {
+				switch config.ConflictPolicy {
+				case ImportConflictFail:
+					return nil, microerror.Maskf(alreadyExistsError, "Vault role '%s' of cluster '%s' already exists", name, config.ID)
+				case ImportConflictSkip:
+					res.Action = "skip"
+				case ImportConflictOverwrite:
+					res.Action = "overwrite"
+				}
+			}
+
+			results = append(results, res)
+			requests = append(requests, req)
+			configs = append(configs, c)
+		}
+	}
+
+	// Write the roles.
+	for i, req := range requests {
+		if results[i].Action == "skip" {
+			continue
+		}
+
+		err := r.Apply(req)
+		if err != nil {
+			return results, microerror.Mask(err)
+		}
+
+		if results[i].Action == "create" {
+			r.audit(AuditOperationCreate, configs[i], nil)
+		} else {
+			previous := existing[results[i].Name]
+			r.audit(AuditOperationUpdate, configs[i], &previous)
+		}
+	}
+
+	// Verify the imported roles.
+	if !r.dryRun {
+		for i, n := range config.Snapshot.Roles {
+			if results[i].Action == "skip" {
+				continue
+			}
+
+			role, err := r.readRole(config.Namespace, config.ID, key.NamedRolePath(config.ID, results[i].Name))
+			if err != nil {
+				return results, microerror.Mask(err)
+			}
+
+			expected := n.Role
+			expected.ID = config.ID
+			expected.CommonName = importCommonName(n.Role, config.Snapshot.ClusterID, config.ID)
+
+			diff, err := snapshotDiff(role, expected)
+			if err != nil {
+				return results, microerror.Mask(err)
+			}
+			if len(diff) > 0 {
+				return results, microerror.Maskf(executionFailedError, "Vault role '%s' does not match the snapshot after importing it, differing in %s", results[i].Name, strings.Join(diff, ", "))
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// importCommonName returns the common name the given role of a snapshot is
+// imported with into the cluster of the given target ID. An empty string is
+// returned in case the snapshot lacks the common name.
+func importCommonName(role Role, snapshotID string, targetID string) string {
+	if snapshotID == "" || snapshotID == targetID {
+		return role.CommonName
+	}
+
+	return strings.Replace(role.CommonName, snapshotID, targetID, -1)
+}
+
+// snapshotDiff returns the fields in which the given imported role differs
+// from the expected role of the snapshot. The common name is only compared in
+// case the snapshot holds it. Extra fields are compared by their JSON
+// encoding, since their types change when snapshots are encoded.
+func snapshotDiff(role Role, expected Role) ([]string, error) {
+	var diff []string
+
+	if managedHash(role) != managedHash(expected) {
+		diff = append(diff, "managed fields")
+	}
+	if expected.CommonName != "" && role.CommonName != expected.CommonName {
+		diff = append(diff, fmt.Sprintf("common name %q, expected %q", role.CommonName, expected.CommonName))
+	}
+
+	var keys []string
+	for k := range expected.Extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		a, err := json.Marshal(role.Extra[k])
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		b, err := json.Marshal(expected.Extra[k])
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if string(a) != string(b) {
+			diff = append(diff, fmt.Sprintf("extra field %q %s, expected %s", k, a, b))
+		}
+	}
+
+	return diff, nil
+}
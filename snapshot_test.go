@@ -0,0 +1,171 @@
+package vaultrole_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/giantswarm/vaultrole"
+	"github.com/giantswarm/vaultrole/key"
+	"github.com/giantswarm/vaultrole/vaultroletest"
+)
+
+func Test_VaultRole_ExportImport(t *testing.T) {
+	newVaultRole := func(s *vaultroletest.Server) *vaultrole.VaultRole {
+		r, err := s.NewVaultRole(vaultrole.Config{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return r
+	}
+
+	source := vaultroletest.NewServer()
+	defer source.Close()
+	source.Mount("", "pki-al9qy")
+
+	target := vaultroletest.NewServer()
+	defer target.Close()
+	target.Mount("", "pki-b3x7k")
+
+	s := newVaultRole(source)
+	for _, c := range []vaultrole.CreateConfig{
+		{ID: "al9qy", AltNames: []string{"api.internal"}, TTL: "1h"},
+		{ID: "al9qy", AllowSubdomains: true, Organizations: []string{"system:masters"}, TTL: "24h"},
+	} {
+		err := s.Create(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snapshot, err := s.Export(vaultrole.ExportConfig{ID: "al9qy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Version != vaultrole.SnapshotVersion || len(snapshot.Roles) != 2 {
+		t.Fatalf("unexpected snapshot %#v", snapshot)
+	}
+
+	// Snapshots must survive a serialisation roundtrip.
+	{
+		b, err := json.Marshal(snapshot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		snapshot = vaultrole.Snapshot{}
+		err = json.Unmarshal(b, &snapshot)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := newVaultRole(target)
+
+	results, err := r.Import(vaultrole.ImportConfig{ID: "b3x7k", Snapshot: snapshot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Name != "role-b3x7k" || results[0].Action != "create" {
+		t.Fatalf("unexpected results %#v", results)
+	}
+
+	{
+		roles, err := r.List(vaultrole.ListConfig{ID: "b3x7k"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(roles) != 2 {
+			t.Fatalf("expected 2 roles got %d", len(roles))
+		}
+		for i, n := range roles {
+			expected := snapshot.Roles[i].Role
+			expected.CommonName = "b3x7k.g8s.gigantic.io"
+			expected.ID = "b3x7k"
+			if vaultrole.RoleHash(n.Role) != vaultrole.RoleHash(expected) {
+				t.Fatalf("expected role %#v got %#v", expected, n.Role)
+			}
+		}
+	}
+
+	_, err = r.Import(vaultrole.ImportConfig{ID: "b3x7k", Snapshot: snapshot})
+	if !vaultrole.IsAlreadyExists(err) {
+		t.Fatalf("expected already exists error got %#v", err)
+	}
+
+	results, err = r.Import(vaultrole.ImportConfig{ConflictPolicy: vaultrole.ImportConflictSkip, ID: "b3x7k", Snapshot: snapshot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Action != "skip" || results[1].Action != "skip" {
+		t.Fatalf("unexpected results %#v", results)
+	}
+
+	results, err = r.Import(vaultrole.ImportConfig{ConflictPolicy: vaultrole.ImportConflictOverwrite, ID: "b3x7k", Snapshot: snapshot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Action != "overwrite" || results[1].Action != "overwrite" {
+		t.Fatalf("unexpected results %#v", results)
+	}
+
+	snapshot.Version = 2
+	_, err = r.Import(vaultrole.ImportConfig{Snapshot: snapshot})
+	if !vaultrole.IsInvalidConfig(err) {
+		t.Fatalf("expected invalid config error got %#v", err)
+	}
+}
+
+func Test_VaultRole_Import_CommonNameExtra(t *testing.T) {
+	source := vaultroletest.NewServer()
+	defer source.Close()
+	source.Mount("", "pki-al9qy")
+
+	target := vaultroletest.NewServer()
+	defer target.Close()
+	target.Mount("", "pki-b3x7k")
+
+	newVaultRole := func(s *vaultroletest.Server, format string) *vaultrole.VaultRole {
+		r, err := s.NewVaultRole(vaultrole.Config{CommonNameFormat: format})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return r
+	}
+
+	s := newVaultRole(source, "%s.g8s.gigantic.io")
+	err := s.Create(vaultrole.CreateConfig{CommonNameFormat: "api.%s.example.com", ID: "al9qy", Organizations: []string{"api"}, TTL: "1h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Set fields not managed by VaultRole, as done by operators using the
+	// Vault CLI.
+	path := key.WriteRolePath("al9qy", []string{"api"})
+	d, _ := source.Data("", path)
+	d["key_type"] = "ec"
+	d["max_ttl"] = "72h"
+	source.SetData("", path, d)
+
+	snapshot, err := s.Export(vaultrole.ExportConfig{ID: "al9qy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := newVaultRole(target, "%s.other.io")
+	_, err = r.Import(vaultrole.ImportConfig{ID: "b3x7k", Snapshot: snapshot})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	role, err := r.Search(vaultrole.SearchConfig{CommonNameFormat: "api.%s.example.com", ID: "b3x7k", Organizations: []string{"api"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if role.CommonName != "api.b3x7k.example.com" {
+		t.Fatalf("CommonName == %q, want %q", role.CommonName, "api.b3x7k.example.com")
+	}
+	if role.Extra["key_type"] != "ec" || role.Extra["max_ttl"] != "72h" {
+		t.Fatalf("unexpected extra fields %#v", role.Extra)
+	}
+}
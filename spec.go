@@ -1,33 +1,196 @@
 package vaultrole
 
-import "time"
+import (
+	"context"
+	"crypto/x509"
+	"time"
+)
+
+// Authenticator authenticates the Vault client used by VaultRole. See package
+// auth for an implementation supporting token renewal and several auth
+// methods, and Config.Authenticator.
+type Authenticator interface {
+	// Login authenticates and sets the resulting token on the Vault client.
+	Login() error
+}
+
+// AuthenticatorRunner is optionally implemented by Authenticators keeping the
+// token of the Vault client alive in the background. VaultRole runs it from
+// New until Stop is called.
+type AuthenticatorRunner interface {
+	// Run keeps the token alive until the given context is done.
+	Run(ctx context.Context)
+}
 
 type CreateConfig struct {
 	AllowBareDomains bool
 	AllowSubdomains  bool
 	AltNames         []string
+	// CommonNameFormat optionally overrides Config.CommonNameFormat and
+	// Config.CommonNameTemplate for this role, e.g. for clusters with
+	// non-standard naming.
+	CommonNameFormat string
+	// CommonNameValues optionally override Config.CommonNameValues.
+	CommonNameValues CommonNameValues
+	ID               string
+	Namespace        string
+	Organizations    []string
+	TTL              string
+	// Profile optionally references a profile registered in Config.Profiles
+	// providing the defaults of the role fields.
+	Profile string
+}
+
+type EnsureConfig struct {
+	AllowBareDomains bool
+	AllowSubdomains  bool
+	AltNames         []string
+	// CommonNameFormat optionally overrides Config.CommonNameFormat and
+	// Config.CommonNameTemplate for this role, e.g. for clusters with
+	// non-standard naming.
+	CommonNameFormat string
+	// CommonNameValues optionally override Config.CommonNameValues.
+	CommonNameValues CommonNameValues
 	ID               string
+	Namespace        string
 	Organizations    []string
 	TTL              string
+	// Profile optionally references a profile registered in Config.Profiles
+	// providing the defaults of the role fields.
+	Profile string
+}
+
+type DeleteConfig struct {
+	ID            string
+	Namespace     string
+	Organizations []string
+}
+
+// EnsureResult describes the outcome of ensuring a single role as part of
+// EnsureMany.
+type EnsureResult struct {
+	ID       string
+	RoleName string
+	// Organizations are the organizations of the role, including the ones
+	// defined by the profile the config referenced.
+	Organizations []string
+	// Created is true in case the role did not exist and got created, false in
+	// case the role existed.
+	Created bool
+	// Unchanged is true in case the role existed with the desired fields and
+	// was not written.
+	Unchanged bool
+	Error     error
 }
 
 type ExistsConfig struct {
 	ID            string
+	Namespace     string
 	Organizations []string
 }
 
-type SearchConfig struct {
+// IssueConfig describes a certificate to be issued using the role of the given
+// cluster ID and organizations.
+type IssueConfig struct {
+	AltNames      []string
+	CommonName    string
 	ID            string
+	IPSANs        []string
+	Namespace     string
 	Organizations []string
+	TTL           string
+}
+
+// IssuedCertificate is a certificate issued for a cluster, as returned by
+// ListCertificates.
+type IssuedCertificate struct {
+	Certificate *x509.Certificate
+	// RoleName is the name of the role matching the organizations of the
+	// certificate, see key.RoleName.
+	RoleName string
+	// RevocationTime is the time the certificate got revoked at, or the zero
+	// time in case it is not revoked.
+	RevocationTime time.Time
+	// SerialNumber is the colon separated serial number of the certificate.
+	SerialNumber string
+}
+
+type ListCertificatesConfig struct {
+	ID        string
+	Namespace string
+}
+
+type ListConfig struct {
+	ID        string
+	Namespace string
+}
+
+// NamedRole is a role along with its name in Vault, as returned by List.
+type NamedRole struct {
+	Name string `json:"name"`
+	Role Role   `json:"role"`
+}
+
+type PlanConfig struct {
+	AllowBareDomains bool
+	AllowSubdomains  bool
+	AltNames         []string
+	// CommonNameFormat optionally overrides Config.CommonNameFormat and
+	// Config.CommonNameTemplate for this role, e.g. for clusters with
+	// non-standard naming.
+	CommonNameFormat string
+	// CommonNameValues optionally override Config.CommonNameValues.
+	CommonNameValues CommonNameValues
+	ID               string
+	Namespace        string
+	Organizations    []string
+	TTL              string
+	// Profile optionally references a profile registered in Config.Profiles
+	// providing the defaults of the role fields.
+	Profile string
+}
+
+type SearchConfig struct {
+	// CommonNameFormat optionally overrides Config.CommonNameFormat and
+	// Config.CommonNameTemplate when verifying the common name of the role.
+	CommonNameFormat string
+	// CommonNameValues optionally override Config.CommonNameValues when
+	// verifying the common name of the role.
+	CommonNameValues CommonNameValues
+	ID               string
+	Namespace        string
+	Organizations    []string
 }
 
 type UpdateConfig struct {
 	AllowBareDomains bool
 	AllowSubdomains  bool
 	AltNames         []string
+	// CommonNameFormat optionally overrides Config.CommonNameFormat and
+	// Config.CommonNameTemplate for this role, e.g. for clusters with
+	// non-standard naming.
+	CommonNameFormat string
+	// CommonNameValues optionally override Config.CommonNameValues.
+	CommonNameValues CommonNameValues
 	ID               string
+	Namespace        string
 	Organizations    []string
 	TTL              string
+	// Profile optionally references a profile registered in Config.Profiles
+	// providing the defaults of the role fields.
+	Profile string
+
+	// Expected optionally enables optimistic concurrency. When set, the current
+	// role is read and the update is refused with a conflict error in case it
+	// differs from Expected, including its common name and extra fields. Note
+	// that this is no check-and-set across replicas, since reading and writing
+	// the role are separate requests and the PKI backend of Vault does not
+	// support check-and-set for roles. Only updates issued through the same
+	// VaultRole are serialised.
+	Expected *Role
+	// ExpectedHash optionally enables optimistic concurrency like Expected,
+	// comparing the hash of the current role as computed by RoleHash instead.
+	ExpectedHash string
 }
 
 type Interface interface {
@@ -37,11 +200,83 @@ type Interface interface {
 	Update(config UpdateConfig) error
 }
 
+// EnsureInterface reconciles many roles at once. It is implemented by
+// VaultRole and used by package manifest.
+type EnsureInterface interface {
+	EnsureMany(configs []EnsureConfig) ([]EnsureResult, error)
+}
+
+// ManageInterface extends Interface by the operations deleting, listing and
+// reconciling roles. It is implemented by VaultRole and used by the packages
+// vaultrolecache and controller.
+type ManageInterface interface {
+	Interface
+	EnsureInterface
+
+	Delete(config DeleteConfig) error
+	List(config ListConfig) ([]NamedRole, error)
+}
+
 type Role struct {
 	AllowBareDomains bool
 	AllowSubdomains  bool
 	AltNames         []string
-	ID               string
-	Organizations    []string
-	TTL              time.Duration
+	// CommonName is the first item of the allowed domains of the role, as
+	// computed using Config.CommonNameFormat when writing the role.
+	CommonName    string `json:",omitempty"`
+	ID            string
+	Organizations []string
+	TTL           time.Duration
+	// Extra holds the fields returned by Vault which are not represented by
+	// any other field of Role, e.g. key_type or max_ttl.
+	Extra map[string]interface{} `json:",omitempty"`
+}
+
+type RevokeConfig struct {
+	ID        string
+	Namespace string
+	// SerialNumber is the colon or hyphen separated serial number of the
+	// certificate to revoke.
+	SerialNumber string
+}
+
+// RevokeRoleConfig describes the role whose certificates are revoked by
+// RevokeRole.
+type RevokeRoleConfig struct {
+	ID            string
+	Namespace     string
+	Organizations []string
+}
+
+// RevokeResult describes the outcome of revoking a single certificate as part
+// of RevokeRole.
+type RevokeResult struct {
+	SerialNumber string
+	// RevocationTime is the time the certificate got revoked at. It is the
+	// zero time in dry run mode.
+	RevocationTime time.Time
+	Error          error
+}
+
+// WriteRequest is the request written to Vault in order to create or update a
+// role, as computed by Plan.
+type WriteRequest struct {
+	Namespace string       `json:"namespace,omitempty"`
+	Path      string       `json:"path"`
+	Payload   WritePayload `json:"payload"`
+}
+
+// WritePayload is the data of a WriteRequest. The JSON encoding matches the
+// data sent to Vault, except for Extra, whose fields are sent to Vault as
+// individual fields.
+type WritePayload struct {
+	AllowBareDomains bool   `json:"allow_bare_domains"`
+	AllowSubdomains  bool   `json:"allow_subdomains"`
+	AllowedDomains   string `json:"allowed_domains"`
+	Organization     string `json:"organization"`
+	TTL              string `json:"ttl"`
+	// Extra holds fields not managed by VaultRole, e.g. key_type or max_ttl,
+	// as restored by Import from Role.Extra. Fields managed by VaultRole are
+	// ignored.
+	Extra map[string]interface{} `json:"extra,omitempty"`
 }
@@ -2,13 +2,67 @@ package vaultrole
 
 import (
 	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/vaultrole/key"
 )
 
 func (r *VaultRole) Update(config UpdateConfig) error {
-	// Check if the requested role exists.
+	// The profile has to be merged first, since it might define the
+	// organizations, which determine the role.
 	{
+		merged, err := r.mergeProfile(updateWriteConfig(config))
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		config.AllowBareDomains = merged.AllowBareDomains
+		config.AllowSubdomains = merged.AllowSubdomains
+		config.AltNames = merged.AltNames
+		config.Organizations = merged.Organizations
+		config.TTL = merged.TTL
+		config.Profile = ""
+	}
+
+	p := lockPath(config.Namespace, config.ID, config.Organizations)
+
+	err := r.locker.Do("update", p, config, func() error {
+		return r.update(config)
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (r *VaultRole) update(config UpdateConfig) error {
+	var previous *Role
+
+	// Check if the requested role still has the expected content. Note that the
+	// PKI backend of Vault does not support check-and-set for roles, so changes
+	// made by other processes between reading and writing the role cannot be
+	// detected.
+	if config.Expected != nil || config.ExpectedHash != "" {
+		current, err := r.readRole(config.Namespace, config.ID, key.ReadRolePath(config.ID, config.Organizations))
+		if IsNotFound(err) {
+			return microerror.Maskf(notFoundError, "cannot update Vault role '%s'", config.ID)
+		} else if err != nil {
+			return microerror.Mask(err)
+		}
+
+		if config.Expected != nil && RoleHash(current) != RoleHash(*config.Expected) {
+			return microerror.Maskf(conflictError, "Vault role '%s' changed since it was read", config.ID)
+		}
+		if config.ExpectedHash != "" && RoleHash(current) != config.ExpectedHash {
+			return microerror.Maskf(conflictError, "Vault role '%s' changed since it was read", config.ID)
+		}
+
+		previous = &current
+	} else {
+		// Check if the requested role exists.
 		c := ExistsConfig{
 			ID:            config.ID,
+			Namespace:     config.Namespace,
 			Organizations: config.Organizations,
 		}
 		exists, err := r.Exists(c)
@@ -18,17 +72,41 @@ func (r *VaultRole) Update(config UpdateConfig) error {
 		if !exists {
 			return microerror.Maskf(notFoundError, "cannot update Vault role '%s'", config.ID)
 		}
+
+		previous, err = r.previousRole(config.Namespace, config.ID, config.Organizations)
+		if err != nil {
+			return microerror.Mask(err)
+		}
 	}
 
 	// Update the requested role if it exists.
 	{
-		c := writeConfig(config)
+		c := updateWriteConfig(config)
 
 		err := r.write(c)
 		if err != nil {
 			return microerror.Mask(err)
 		}
+
+		r.audit(AuditOperationUpdate, c, previous)
 	}
 
 	return nil
 }
+
+func updateWriteConfig(config UpdateConfig) writeConfig {
+	c := writeConfig{
+		AllowBareDomains: config.AllowBareDomains,
+		AllowSubdomains:  config.AllowSubdomains,
+		AltNames:         config.AltNames,
+		CommonNameFormat: config.CommonNameFormat,
+		CommonNameValues: config.CommonNameValues,
+		ID:               config.ID,
+		Namespace:        config.Namespace,
+		Organizations:    config.Organizations,
+		TTL:              config.TTL,
+		Profile:          config.Profile,
+	}
+
+	return c
+}
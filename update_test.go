@@ -0,0 +1,104 @@
+package vaultrole_test
+
+import (
+	"testing"
+
+	"github.com/giantswarm/vaultrole"
+	"github.com/giantswarm/vaultrole/key"
+	"github.com/giantswarm/vaultrole/vaultroletest"
+)
+
+func Test_VaultRole_Update_Expected(t *testing.T) {
+	testCases := []struct {
+		name         string
+		change       func(data map[string]interface{})
+		byHash       bool
+		errorMatcher func(error) bool
+	}{
+		{
+			name:   "case 0: unchanged role is updated",
+			change: func(data map[string]interface{}) {},
+		},
+		{
+			name: "case 1: changed TTL is detected",
+			change: func(data map[string]interface{}) {
+				data["ttl"] = "2h"
+			},
+			errorMatcher: vaultrole.IsConflict,
+		},
+		{
+			name: "case 2: changed common name is detected",
+			change: func(data map[string]interface{}) {
+				data["allowed_domains"] = []interface{}{"al9qy.k8s.gigantic.io"}
+			},
+			errorMatcher: vaultrole.IsConflict,
+		},
+		{
+			name: "case 3: changed extra field is detected",
+			change: func(data map[string]interface{}) {
+				data["key_type"] = "ec"
+			},
+			errorMatcher: vaultrole.IsConflict,
+		},
+		{
+			name: "case 4: changed extra field is detected by hash",
+			change: func(data map[string]interface{}) {
+				data["key_type"] = "ec"
+			},
+			byHash:       true,
+			errorMatcher: vaultrole.IsConflict,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := vaultroletest.NewServer()
+			defer s.Close()
+			s.Mount("", "pki-al9qy")
+
+			r, err := s.NewVaultRole(vaultrole.Config{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = r.Create(vaultrole.CreateConfig{ID: "al9qy", Organizations: []string{"api"}, TTL: "1h"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected, err := r.Search(vaultrole.SearchConfig{ID: "al9qy", Organizations: []string{"api"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Another process changes the role after it got read.
+			p := key.WriteRolePath("al9qy", []string{"api"})
+			data, ok := s.Data("", p)
+			if !ok {
+				t.Fatalf("expected role to exist")
+			}
+			tc.change(data)
+			s.SetData("", p, data)
+
+			config := vaultrole.UpdateConfig{ID: "al9qy", Organizations: []string{"api"}, TTL: "3h"}
+			if tc.byHash {
+				config.ExpectedHash = vaultrole.RoleHash(expected)
+			} else {
+				config.Expected = &expected
+			}
+
+			err = r.Update(config)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+		})
+	}
+}
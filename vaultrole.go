@@ -1,85 +1,328 @@
 package vaultrole
 
 import (
+	"context"
 	"strings"
+	"text/template"
 
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 	vaultclient "github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/sdk/helper/parseutil"
 
 	"github.com/giantswarm/vaultrole/key"
 )
 
 type Config struct {
-	Logger      micrologger.Logger
+	// Context is the context all requests issued against Vault and the
+	// background renewal started for Config.Authenticator are bound to.
+	// Cancelling it, e.g. on shutdown, aborts in-flight requests, including
+	// the retries of middleware.NewRetry. Defaults to context.Background().
+	Context context.Context
+	Logger  micrologger.Logger
+	// VaultClient is the Vault API client requests are issued with. Either
+	// VaultClient or LogicalClient must be configured.
 	VaultClient *vaultclient.Client
+	// LogicalClient is the client requests are issued with, e.g. a client
+	// returned by NewLogicalClient wrapped with middlewares using
+	// ChainLogicalClient. Either VaultClient or LogicalClient must be
+	// configured.
+	LogicalClient LogicalClient
 
+	// AuditSink is optional. When configured, an AuditEvent is emitted for
+	// every successful change of a role.
+	AuditSink AuditSink
+	// Authenticator is optional. When configured, New logs in initially and
+	// requests denied by Vault are retried once after re-authenticating the
+	// Vault client. In case the Authenticator implements AuthenticatorRunner,
+	// e.g. auth.Authenticator, New additionally keeps the token alive in the
+	// background until VaultRole.Stop is called.
+	Authenticator Authenticator
+
+	// AuditActor is recorded as actor of emitted audit events, e.g. the name
+	// of the operator using VaultRole.
+	AuditActor string
+	// CommonNameFormat is the fmt format computing the common name of roles
+	// from the cluster ID, e.g. "%s.g8s.example.com". It must contain exactly
+	// one %s verb. Either CommonNameFormat or CommonNameTemplate must be
+	// configured.
 	CommonNameFormat string
+	// CommonNameTemplate is the text/template computing the common name of
+	// roles, e.g. "{{.ClusterID}}.k8s.{{.Region}}.{{.BaseDomain}}". The
+	// variables BaseDomain, ClusterID, Organization and Region are available.
+	CommonNameTemplate string
+	// CommonNameValues are the default values of the variables available to
+	// CommonNameTemplate.
+	CommonNameValues CommonNameValues
+	// DryRun, when true, makes all mutating operations perform their reads and
+	// validation and log the path and payload they would write, without
+	// actually writing anything to Vault.
+	DryRun bool
+	// EnsureConcurrency is the maximum number of concurrent writes issued by
+	// EnsureMany. Defaults to 10.
+	EnsureConcurrency int
+	// ManagePolicies, when true, makes VaultRole write an ACL policy granting
+	// issuing and signing certificates using a role whenever the role is
+	// written by Create, Update, EnsureMany, Apply or Import, and delete the
+	// policy when the role is deleted. Policies are written before and deleted
+	// before their roles, and are reconciled even in case Create finds the
+	// role existing or Delete finds it missing, so that failed operations can
+	// be retried. See RenderPolicy and key.PolicyName.
+	ManagePolicies bool
+	// Profiles are the named profiles operations can reference in order to
+	// use common defaults for the role fields.
+	Profiles map[string]Profile
 }
 
 func DefaultConfig() Config {
 	config := Config{
-		Logger:      nil,
-		VaultClient: nil,
+		Context:       nil,
+		Logger:        nil,
+		VaultClient:   nil,
+		LogicalClient: nil,
+
+		AuditSink:     nil,
+		Authenticator: nil,
 
-		CommonNameFormat: "",
+		AuditActor:         "",
+		CommonNameFormat:   "",
+		CommonNameTemplate: "",
+		CommonNameValues:   CommonNameValues{},
+		DryRun:             false,
+		EnsureConcurrency:  10,
+		ManagePolicies:     false,
+		Profiles:           nil,
 	}
 
 	return config
 }
 
+// VaultRole is safe for concurrent use. Create, Update and EnsureMany are
+// serialised per role path as computed by key.WriteRolePath, so that checking
+// for the existence of a role and writing it cannot interleave with another
+// operation on the same role within the process. Concurrent calls of the same
+// operation with an equal config are executed only once and share the result.
+// Operations issued by other processes are not covered.
 type VaultRole struct {
-	logger      micrologger.Logger
-	vaultClient *vaultclient.Client
+	ctx           context.Context
+	logger        micrologger.Logger
+	logicalClient LogicalClient
+	locker        *pathLocker
+
+	auditSink     AuditSink
+	authenticator Authenticator
+
+	// stopRun stops the AuthenticatorRunner started by New, runDone is closed
+	// once it returned. Both are nil in case no runner got started.
+	stopRun context.CancelFunc
+	runDone chan struct{}
 
-	commonNameFormat string
+	auditActor         string
+	commonNameFormat   string
+	commonNameTemplate *template.Template
+	commonNameValues   CommonNameValues
+	dryRun             bool
+	ensureConcurrency  int
+	managePolicies     bool
+	profiles           map[string]Profile
 }
 
 func New(config Config) (*VaultRole, error) {
 	if config.Logger == nil {
 		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
 	}
-	if config.VaultClient == nil {
-		return nil, microerror.Maskf(invalidConfigError, "config.VaultClient must not be empty")
+	if config.VaultClient == nil && config.LogicalClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.VaultClient or config.LogicalClient must not be empty")
+	}
+	if config.VaultClient != nil && config.LogicalClient != nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.VaultClient and config.LogicalClient must not both be given")
+	}
+
+	if config.CommonNameFormat == "" && config.CommonNameTemplate == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.CommonNameFormat or config.CommonNameTemplate must not be empty")
+	}
+	if config.CommonNameFormat != "" && config.CommonNameTemplate != "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.CommonNameFormat and config.CommonNameTemplate must not both be given")
+	}
+	if config.CommonNameFormat != "" {
+		err := validateCommonNameFormat(config.CommonNameFormat)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+	var commonNameTemplate *template.Template
+	if config.CommonNameTemplate != "" {
+		var err error
+		commonNameTemplate, err = parseCommonNameTemplate(config.CommonNameTemplate)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+	if config.EnsureConcurrency < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.EnsureConcurrency must not be negative")
+	}
+	if config.EnsureConcurrency == 0 {
+		config.EnsureConcurrency = 10
+	}
+	for name := range config.Profiles {
+		if name == "" {
+			return nil, microerror.Maskf(invalidConfigError, "config.Profiles must not contain empty names")
+		}
 	}
 
-	if config.CommonNameFormat == "" {
-		return nil, microerror.Maskf(invalidConfigError, "config.CommonNameFormat must not be empty")
+	logicalClient := config.LogicalClient
+	if logicalClient == nil {
+		logicalClient = NewLogicalClient(config.VaultClient)
+	}
+
+	if config.Context == nil {
+		config.Context = context.Background()
 	}
 
 	r := &VaultRole{
-		logger:      config.Logger,
-		vaultClient: config.VaultClient,
+		ctx:           config.Context,
+		logger:        config.Logger,
+		logicalClient: logicalClient,
+		locker:        newPathLocker(),
+
+		auditSink:     config.AuditSink,
+		authenticator: config.Authenticator,
+
+		auditActor:         config.AuditActor,
+		commonNameFormat:   config.CommonNameFormat,
+		commonNameTemplate: commonNameTemplate,
+		commonNameValues:   config.CommonNameValues,
+		dryRun:             config.DryRun,
+		ensureConcurrency:  config.EnsureConcurrency,
+		managePolicies:     config.ManagePolicies,
+		profiles:           config.Profiles,
+	}
+
+	// Log in initially and keep the token alive in the background, so that
+	// callers do not have to manage the token lifecycle themselves.
+	if r.authenticator != nil {
+		err := r.authenticator.Login()
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		runner, ok := r.authenticator.(AuthenticatorRunner)
+		if ok {
+			ctx, cancel := context.WithCancel(r.ctx)
+			r.stopRun = cancel
+			r.runDone = make(chan struct{})
 
-		commonNameFormat: config.CommonNameFormat,
+			go func() {
+				defer close(r.runDone)
+				runner.Run(ctx)
+			}()
+		}
 	}
 
 	return r, nil
 }
 
+// Stop stops keeping the token of the Vault client alive, see
+// Config.Authenticator, and blocks until the background renewal returned.
+// Requests are not affected, see Config.Context.
+// Stop is safe to call multiple times and in case no renewal got started.
+func (r *VaultRole) Stop() {
+	if r.stopRun == nil {
+		return
+	}
+
+	r.stopRun()
+	<-r.runDone
+}
+
 type writeConfig struct {
 	AllowBareDomains bool
 	AllowSubdomains  bool
 	AltNames         []string
+	CommonNameFormat string
+	CommonNameValues CommonNameValues
 	ID               string
+	Namespace        string
 	Organizations    []string
 	TTL              string
+	Profile          string
 }
 
 func (r *VaultRole) write(config writeConfig) error {
-	k := key.WriteRolePath(config.ID, config.Organizations)
-	v := map[string]interface{}{
-		"allow_bare_domains": config.AllowBareDomains,
-		"allow_subdomains":   config.AllowSubdomains,
-		"allowed_domains":    key.AllowedDomains(config.ID, r.commonNameFormat, config.AltNames),
-		"organization":       strings.Join(config.Organizations, ","),
-		"ttl":                config.TTL,
+	req, err := r.Plan(PlanConfig(config))
+	if err != nil {
+		return microerror.Mask(err)
 	}
 
-	_, err := r.vaultClient.Logical().Write(k, v)
+	// The policy is written before the role, so that a role never exists
+	// without its policy. Writing the policy is idempotent, so retrying a
+	// failed write is safe.
+	err = r.writePolicy(config.Namespace, config.ID, config.Organizations)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = r.apply(req)
 	if err != nil {
 		return microerror.Mask(err)
 	}
 
 	return nil
 }
+
+// validateWriteConfig checks the given config before anything gets written to
+// Vault.
+func validateWriteConfig(config writeConfig) error {
+	if config.ID == "" {
+		return microerror.Maskf(invalidConfigError, "config.ID must not be empty")
+	}
+	if config.CommonNameFormat != "" {
+		err := validateCommonNameFormat(config.CommonNameFormat)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+	if config.TTL != "" {
+		_, err := parseutil.ParseDurationSecond(config.TTL)
+		if err != nil {
+			return microerror.Maskf(invalidConfigError, "config.TTL must be a valid duration: %s", err.Error())
+		}
+	}
+	for _, o := range config.Organizations {
+		if o == "" || strings.Contains(o, ",") {
+			return microerror.Maskf(invalidConfigError, "config.Organizations must not contain empty items or items containing commas")
+		}
+	}
+	for _, a := range config.AltNames {
+		if a == "" || strings.Contains(a, ",") {
+			return microerror.Maskf(invalidConfigError, "config.AltNames must not contain empty items or items containing commas")
+		}
+	}
+
+	return nil
+}
+
+// withReauth executes fn. In case Vault denies the request issued by fn and an
+// Authenticator is configured, the Vault client is re-authenticated and fn is
+// executed once more.
+func (r *VaultRole) withReauth(fn func() error) error {
+	err := fn()
+	if r.authenticator == nil || !IsPermissionDenied(err) {
+		return err
+	}
+
+	r.logger.Log("level", "debug", "message", "re-authenticating Vault client after request got denied")
+
+	err = r.authenticator.Login()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return fn()
+}
+
+// lockPath returns the path used to serialise operations on the role
+// identified by the given namespace, cluster ID and organizations.
+func lockPath(namespace string, ID string, organizations []string) string {
+	return namespace + "/" + key.WriteRolePath(ID, organizations)
+}
@@ -0,0 +1,287 @@
+// Package vaultrolecache provides a read-through cache for
+// vaultrole.ManageInterface.
+// Results of Exists and Search are cached per role for a configurable TTL.
+// Search results indicating that a role could not be found are cached as well.
+// Mutating operations issued through the same instance invalidate the cache
+// entries of the affected clusters.
+package vaultrolecache
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/vaultrole"
+	"github.com/giantswarm/vaultrole/key"
+)
+
+type Config struct {
+	VaultRole vaultrole.ManageInterface
+
+	// NotFoundTTL is the time Search results indicating that a role does not
+	// exist are cached. Defaults to TTL.
+	NotFoundTTL time.Duration
+	// TTL is the time results of Exists and Search are cached.
+	TTL time.Duration
+}
+
+// Stats provides the number of cache hits and misses of Exists and Search.
+type Stats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+type VaultRoleCache struct {
+	vaultRole vaultrole.ManageInterface
+
+	notFoundTTL time.Duration
+	ttl         time.Duration
+
+	mutex  sync.Mutex
+	exists map[string]existsEntry
+	search map[string]searchEntry
+	stats  Stats
+	// generations counts the invalidations per namespace and cluster ID.
+	// Results read from Vault are only stored in case the generation of their
+	// cluster did not change while reading them, since an entry stored after
+	// an invalidation might predate the change it was invalidated for.
+	generations map[string]uint64
+
+	now func() time.Time
+}
+
+type existsEntry struct {
+	exists  bool
+	expires time.Time
+}
+
+type searchEntry struct {
+	role    vaultrole.Role
+	err     error
+	expires time.Time
+}
+
+func New(config Config) (*VaultRoleCache, error) {
+	if config.VaultRole == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.VaultRole must not be empty")
+	}
+
+	if config.TTL <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.TTL must be greater than 0")
+	}
+	if config.NotFoundTTL < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.NotFoundTTL must not be negative")
+	}
+	if config.NotFoundTTL == 0 {
+		config.NotFoundTTL = config.TTL
+	}
+
+	c := &VaultRoleCache{
+		vaultRole: config.VaultRole,
+
+		notFoundTTL: config.NotFoundTTL,
+		ttl:         config.TTL,
+
+		exists:      map[string]existsEntry{},
+		search:      map[string]searchEntry{},
+		generations: map[string]uint64{},
+
+		now: time.Now,
+	}
+
+	return c, nil
+}
+
+func (c *VaultRoleCache) Create(config vaultrole.CreateConfig) error {
+	defer c.invalidate(config.Namespace, config.ID)
+
+	err := c.vaultRole.Create(config)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (c *VaultRoleCache) Delete(config vaultrole.DeleteConfig) error {
+	defer c.invalidate(config.Namespace, config.ID)
+
+	err := c.vaultRole.Delete(config)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (c *VaultRoleCache) EnsureMany(configs []vaultrole.EnsureConfig) ([]vaultrole.EnsureResult, error) {
+	defer func() {
+		for _, config := range configs {
+			c.invalidate(config.Namespace, config.ID)
+		}
+	}()
+
+	results, err := c.vaultRole.EnsureMany(configs)
+	if err != nil {
+		return results, microerror.Mask(err)
+	}
+
+	return results, nil
+}
+
+func (c *VaultRoleCache) Exists(config vaultrole.ExistsConfig) (bool, error) {
+	k := cacheKey(config.Namespace, config.ID, config.Organizations)
+
+	c.mutex.Lock()
+	e, ok := c.exists[k]
+	if ok && c.now().Before(e.expires) {
+		c.stats.Hits++
+		c.mutex.Unlock()
+		return e.exists, nil
+	}
+	c.stats.Misses++
+	g := c.generations[clusterKey(config.Namespace, config.ID)]
+	c.mutex.Unlock()
+
+	exists, err := c.vaultRole.Exists(config)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	c.mutex.Lock()
+	if c.generations[clusterKey(config.Namespace, config.ID)] == g {
+		c.exists[k] = existsEntry{
+			exists:  exists,
+			expires: c.now().Add(c.ttl),
+		}
+	}
+	c.mutex.Unlock()
+
+	return exists, nil
+}
+
+// List is not cached.
+func (c *VaultRoleCache) List(config vaultrole.ListConfig) ([]vaultrole.NamedRole, error) {
+	roles, err := c.vaultRole.List(config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return roles, nil
+}
+
+func (c *VaultRoleCache) Search(config vaultrole.SearchConfig) (vaultrole.Role, error) {
+	k := searchKey(config)
+
+	c.mutex.Lock()
+	e, ok := c.search[k]
+	if ok && c.now().Before(e.expires) {
+		c.stats.Hits++
+		c.mutex.Unlock()
+		if e.err != nil {
+			return vaultrole.Role{}, microerror.Mask(e.err)
+		}
+		return e.role, nil
+	}
+	c.stats.Misses++
+	g := c.generations[clusterKey(config.Namespace, config.ID)]
+	c.mutex.Unlock()
+
+	role, err := c.vaultRole.Search(config)
+	if vaultrole.IsNotFound(err) {
+		c.mutex.Lock()
+		if c.generations[clusterKey(config.Namespace, config.ID)] == g {
+			c.search[k] = searchEntry{
+				err:     err,
+				expires: c.now().Add(c.notFoundTTL),
+			}
+		}
+		c.mutex.Unlock()
+
+		return vaultrole.Role{}, microerror.Mask(err)
+	} else if vaultrole.IsCommonNameMismatch(err) {
+		return role, microerror.Mask(err)
+	} else if err != nil {
+		return vaultrole.Role{}, microerror.Mask(err)
+	}
+
+	c.mutex.Lock()
+	if c.generations[clusterKey(config.Namespace, config.ID)] == g {
+		c.search[k] = searchEntry{
+			role:    role,
+			expires: c.now().Add(c.ttl),
+		}
+	}
+	c.mutex.Unlock()
+
+	return role, nil
+}
+
+// Stats returns the number of cache hits and misses observed so far.
+func (c *VaultRoleCache) Stats() Stats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.stats
+}
+
+func (c *VaultRoleCache) Update(config vaultrole.UpdateConfig) error {
+	defer c.invalidate(config.Namespace, config.ID)
+
+	err := c.vaultRole.Update(config)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// invalidate drops the cache entries of all roles of the given namespace and
+// cluster ID. All roles of the cluster are affected, since the organizations
+// determining the changed role might be defined by a profile the cache does
+// not know about. Entries are dropped regardless of the outcome of the
+// mutating operation, since a failed write might still have been applied by
+// Vault. Reads in flight while invalidating do not store their results, see
+// generations.
+func (c *VaultRoleCache) invalidate(namespace string, ID string) {
+	prefix := clusterKey(namespace, ID)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.generations[prefix]++
+
+	for k := range c.exists {
+		if strings.HasPrefix(k, prefix) {
+			delete(c.exists, k)
+		}
+	}
+	for k := range c.search {
+		if strings.HasPrefix(k, prefix) {
+			delete(c.search, k)
+		}
+	}
+}
+
+func clusterKey(namespace string, ID string) string {
+	return namespace + "/" + key.ListRolesPath(ID)
+}
+
+func cacheKey(namespace string, ID string, organizations []string) string {
+	// The organizations are copied since computing the role name sorts them in
+	// place.
+	orgs := append([]string(nil), organizations...)
+	return namespace + "/" + key.ReadRolePath(ID, orgs)
+}
+
+// searchKey returns the cache key of the given search. The common name format
+// and values are part of the key, since the verification of the common name
+// depends on them.
+func searchKey(config vaultrole.SearchConfig) string {
+	v := config.CommonNameValues
+
+	return cacheKey(config.Namespace, config.ID, config.Organizations) + fmt.Sprintf("#%q#%q#%q#%q", config.CommonNameFormat, v.BaseDomain, v.Organization, v.Region)
+}
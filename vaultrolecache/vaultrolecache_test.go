@@ -0,0 +1,277 @@
+package vaultrolecache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	vaultclient "github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/vaultrole"
+	"github.com/giantswarm/vaultrole/vaultroletest"
+)
+
+// countingVaultRole counts the calls issued to the underlying
+// vaultrole.ManageInterface and reports roles as existing once they got created.
+type countingVaultRole struct {
+	*vaultroletest.VaultRoleTest
+
+	created  map[string]bool
+	notFound error
+	exists   int
+	searches int
+}
+
+func (r *countingVaultRole) Create(config vaultrole.CreateConfig) error {
+	r.created[config.ID] = true
+	return nil
+}
+
+func (r *countingVaultRole) Exists(config vaultrole.ExistsConfig) (bool, error) {
+	r.exists++
+	return r.created[config.ID], nil
+}
+
+func (r *countingVaultRole) Search(config vaultrole.SearchConfig) (vaultrole.Role, error) {
+	r.searches++
+	if !r.created[config.ID] {
+		return vaultrole.Role{}, r.notFound
+	}
+	return vaultrole.Role{ID: config.ID}, nil
+}
+
+// blockingVaultRole blocks Search until release is closed, once started is
+// closed, allowing tests to interleave mutating operations with searches.
+type blockingVaultRole struct {
+	*vaultroletest.VaultRoleTest
+
+	release  chan struct{}
+	started  chan struct{}
+	searches int
+	ttl      time.Duration
+}
+
+func (r *blockingVaultRole) Search(config vaultrole.SearchConfig) (vaultrole.Role, error) {
+	r.searches++
+	ttl := r.ttl
+	if r.searches == 1 {
+		close(r.started)
+		<-r.release
+	}
+	return vaultrole.Role{ID: config.ID, TTL: ttl}, nil
+}
+
+func (r *blockingVaultRole) Update(config vaultrole.UpdateConfig) error {
+	ttl, err := time.ParseDuration(config.TTL)
+	if err != nil {
+		return err
+	}
+	r.ttl = ttl
+	return nil
+}
+
+// newNotFoundError returns the error vaultrole.VaultRole returns when
+// searching a role which does not exist.
+func newNotFoundError(t *testing.T) error {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer s.Close()
+
+	client, err := vaultclient.NewClient(&vaultclient.Config{Address: s.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := vaultrole.New(vaultrole.Config{
+		Logger:           microloggertest.New(),
+		VaultClient:      client,
+		CommonNameFormat: "%s.g8s.gigantic.io",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = r.Search(vaultrole.SearchConfig{ID: "al9qy"})
+	if !vaultrole.IsNotFound(err) {
+		t.Fatalf("expected not found error got %#v", err)
+	}
+
+	return err
+}
+
+func Test_VaultRoleCache_Interface(t *testing.T) {
+	var _ vaultrole.Interface = &VaultRoleCache{}
+	var _ vaultrole.ManageInterface = &VaultRoleCache{}
+}
+
+func Test_VaultRoleCache_Exists(t *testing.T) {
+	u := &countingVaultRole{created: map[string]bool{}}
+
+	c, err := New(Config{VaultRole: u, TTL: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		exists, err := c.Exists(vaultrole.ExistsConfig{ID: "al9qy", Organizations: []string{"api"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exists {
+			t.Fatalf("expected role to not exist")
+		}
+	}
+	if u.exists != 1 {
+		t.Fatalf("expected 1 underlying call got %d", u.exists)
+	}
+
+	// Creating the role through the cache invalidates the cached result.
+	err = c.Create(vaultrole.CreateConfig{ID: "al9qy", Organizations: []string{"api"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exists, err := c.Exists(vaultrole.ExistsConfig{ID: "al9qy", Organizations: []string{"api"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exists {
+		t.Fatalf("expected role to exist")
+	}
+	if u.exists != 2 {
+		t.Fatalf("expected 2 underlying calls got %d", u.exists)
+	}
+
+	// Cached results expire after the TTL.
+	now = now.Add(2 * time.Minute)
+	_, err = c.Exists(vaultrole.ExistsConfig{ID: "al9qy", Organizations: []string{"api"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.exists != 3 {
+		t.Fatalf("expected 3 underlying calls got %d", u.exists)
+	}
+
+	s := c.Stats()
+	if s.Hits != 2 || s.Misses != 3 {
+		t.Fatalf("expected 2 hits and 3 misses got %#v", s)
+	}
+}
+
+func Test_VaultRoleCache_Search_NotFound(t *testing.T) {
+	u := &countingVaultRole{created: map[string]bool{}, notFound: newNotFoundError(t)}
+
+	c, err := New(Config{VaultRole: u, TTL: time.Minute, NotFoundTTL: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		_, err := c.Search(vaultrole.SearchConfig{ID: "al9qy"})
+		if !vaultrole.IsNotFound(err) {
+			t.Fatalf("expected not found error got %#v", err)
+		}
+	}
+	if u.searches != 1 {
+		t.Fatalf("expected 1 underlying call got %d", u.searches)
+	}
+
+	// Negative results expire after the NotFoundTTL.
+	now = now.Add(2 * time.Second)
+	u.created["al9qy"] = true
+	role, err := c.Search(vaultrole.SearchConfig{ID: "al9qy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if role.ID != "al9qy" {
+		t.Fatalf("expected role al9qy got %#v", role)
+	}
+	if u.searches != 2 {
+		t.Fatalf("expected 2 underlying calls got %d", u.searches)
+	}
+}
+
+func Test_VaultRoleCache_Search_CommonNameValues(t *testing.T) {
+	s := vaultroletest.NewServer()
+	defer s.Close()
+	s.Mount("", "pki-al9qy")
+
+	r, err := s.NewVaultRole(vaultrole.Config{CommonNameTemplate: "{{.ClusterID}}.k8s.{{.Region}}.gigantic.io"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := New(Config{VaultRole: r, TTL: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eu := vaultrole.CommonNameValues{Region: "eu"}
+	err = c.Create(vaultrole.CreateConfig{ID: "al9qy", CommonNameValues: eu, TTL: "1h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.Search(vaultrole.SearchConfig{ID: "al9qy", CommonNameValues: eu})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.Search(vaultrole.SearchConfig{ID: "al9qy", CommonNameValues: vaultrole.CommonNameValues{Region: "us"}})
+	if !vaultrole.IsCommonNameMismatch(err) {
+		t.Fatalf("expected common name mismatch error got %#v", err)
+	}
+}
+
+func Test_VaultRoleCache_Search_Update(t *testing.T) {
+	u := &blockingVaultRole{
+		release: make(chan struct{}),
+		started: make(chan struct{}),
+		ttl:     time.Hour,
+	}
+
+	c, err := New(Config{VaultRole: u, TTL: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan vaultrole.Role)
+	go func() {
+		role, err := c.Search(vaultrole.SearchConfig{ID: "al9qy"})
+		if err != nil {
+			t.Error(err)
+		}
+		done <- role
+	}()
+
+	// The role is updated while the search is in flight. The search returns
+	// the role it read before the update, but must not cache it.
+	<-u.started
+	err = c.Update(vaultrole.UpdateConfig{ID: "al9qy", TTL: "2h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(u.release)
+
+	role := <-done
+	if role.TTL != time.Hour {
+		t.Fatalf("expected TTL 1h got %s", role.TTL)
+	}
+
+	role, err = c.Search(vaultrole.SearchConfig{ID: "al9qy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if role.TTL != 2*time.Hour {
+		t.Fatalf("expected TTL 2h got %s", role.TTL)
+	}
+	if u.searches != 2 {
+		t.Fatalf("expected 2 underlying calls got %d", u.searches)
+	}
+}
@@ -0,0 +1,12 @@
+package vaultroletest
+
+import "github.com/giantswarm/microerror"
+
+var invalidRequestError = &microerror.Error{
+	Kind: "invalidRequestError",
+}
+
+// IsInvalidRequest asserts invalidRequestError.
+func IsInvalidRequest(err error) bool {
+	return microerror.Cause(err) == invalidRequestError
+}
@@ -0,0 +1,267 @@
+package vaultroletest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+)
+
+// certificateAuthority is the CA of an emulated PKI backend.
+type certificateAuthority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  string
+}
+
+// isIssuePath returns true for paths of the form <mount>/issue/<role>.
+func isIssuePath(path string) bool {
+	parts := strings.Split(path, "/")
+	return len(parts) == 3 && parts[1] == "issue"
+}
+
+// issue emulates issuing a certificate using the role at the given issue path
+// the way the PKI backend of Vault does. The common name, alternative names,
+// IP SANs and TTL are taken from the request, the organizations from the role.
+// The TTL defaults to the TTL of the role. Issued certificates are stored like
+// Vault stores them, see storeCertificate. The caller must hold the mutex.
+func (s *Server) issue(namespace, path string, req map[string]interface{}) (map[string]interface{}, error) {
+	parts := strings.Split(path, "/")
+	mount, role := parts[0], parts[2]
+
+	r, ok := s.data[entryKey{namespace: namespace, path: mount + "/roles/" + role}]
+	if !ok {
+		return nil, microerror.Maskf(invalidRequestError, "unknown role: %s", role)
+	}
+
+	commonName, _ := req["common_name"].(string)
+	if commonName == "" {
+		return nil, microerror.Maskf(invalidRequestError, "the common_name field is required")
+	}
+
+	ttl := 768 * time.Hour
+	if v, ok := r["ttl"].(int); ok && v > 0 {
+		ttl = time.Duration(v) * time.Second
+	}
+	if v, ok := req["ttl"].(string); ok && v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			ttl = time.Duration(n) * time.Second
+		} else {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, microerror.Maskf(invalidRequestError, "invalid ttl: %s", err.Error())
+			}
+			ttl = d
+		}
+	}
+
+	var organizations []string
+	if list, ok := r["organization"].([]interface{}); ok {
+		for _, o := range list {
+			organizations = append(organizations, o.(string))
+		}
+	}
+
+	dnsNames := []string{commonName}
+	if v, ok := req["alt_names"].(string); ok && v != "" {
+		dnsNames = append(dnsNames, strings.Split(v, ",")...)
+	}
+
+	var ips []net.IP
+	if v, ok := req["ip_sans"].(string); ok && v != "" {
+		for _, s := range strings.Split(v, ",") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, microerror.Maskf(invalidRequestError, "invalid IP SAN %q", s)
+			}
+			ips = append(ips, ip)
+		}
+	}
+
+	ca, err := s.certificateAuthority(namespace, mount)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   commonName,
+			Organization: organizations,
+		},
+		DNSNames:    dnsNames,
+		IPAddresses: ips,
+		NotBefore:   now,
+		NotAfter:    now.Add(ttl),
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	certPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	keyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	serialNumber := formatSerial(serial)
+
+	s.storeCertificate(namespace, mount, serialNumber, certPEM)
+
+	d := map[string]interface{}{
+		"ca_chain":         []string{ca.pem},
+		"certificate":      certPEM,
+		"expiration":       template.NotAfter.Unix(),
+		"issuing_ca":       ca.pem,
+		"private_key":      keyPEM,
+		"private_key_type": "ec",
+		"serial_number":    serialNumber,
+	}
+
+	return d, nil
+}
+
+// certificateAuthority returns the CA of the PKI backend mounted at the given
+// path, generating it on first use. The caller must hold the mutex.
+func (s *Server) certificateAuthority(namespace, mount string) (*certificateAuthority, error) {
+	k := entryKey{namespace: namespace, path: mount}
+	if ca, ok := s.cas[k]; ok {
+		return ca, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: mount},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(10 * 365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	ca := &certificateAuthority{
+		cert: cert,
+		key:  key,
+		pem:  string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+	}
+	s.cas[k] = ca
+
+	// Vault lists the certificate of the CA along with the issued ones.
+	s.storeCertificate(namespace, mount, formatSerial(cert.SerialNumber), ca.pem)
+
+	return ca, nil
+}
+
+// isCertPath returns true for paths of the form <mount>/cert/<serial>.
+func isCertPath(path string) bool {
+	parts := strings.Split(path, "/")
+	return len(parts) == 3 && parts[1] == "cert"
+}
+
+// isRevokePath returns true for paths of the form <mount>/revoke.
+func isRevokePath(path string) bool {
+	parts := strings.Split(path, "/")
+	return len(parts) == 2 && parts[1] == "revoke"
+}
+
+// storeCertificate stores the given certificate at <mount>/certs/<serial>,
+// where the serial number is hyphen separated, so that listing <mount>/certs
+// returns serial numbers the way Vault does. The caller must hold the mutex.
+func (s *Server) storeCertificate(namespace, mount, serialNumber, certPEM string) {
+	s.data[certKey(namespace, mount, serialNumber)] = map[string]interface{}{
+		"certificate":     certPEM,
+		"revocation_time": 0,
+	}
+}
+
+// certificate returns the certificate stored for the given cert path, which
+// accepts colon or hyphen separated serial numbers like Vault does. The caller
+// must hold the mutex.
+func (s *Server) certificate(namespace, path string) (map[string]interface{}, bool) {
+	parts := strings.Split(path, "/")
+
+	d, ok := s.data[certKey(namespace, parts[0], parts[2])]
+	return d, ok
+}
+
+// revoke emulates revoking the certificate of the serial number given by the
+// request. Revoking a revoked certificate returns the original revocation
+// time. The caller must hold the mutex.
+func (s *Server) revoke(namespace, path string, req map[string]interface{}) (map[string]interface{}, error) {
+	mount := strings.Split(path, "/")[0]
+
+	serialNumber, _ := req["serial_number"].(string)
+	if serialNumber == "" {
+		return nil, microerror.Maskf(invalidRequestError, "the serial_number field is required")
+	}
+
+	d, ok := s.data[certKey(namespace, mount, serialNumber)]
+	if !ok {
+		return nil, microerror.Maskf(invalidRequestError, "certificate with serial %s not found", serialNumber)
+	}
+
+	if t, ok := d["revocation_time"].(int64); ok && t > 0 {
+		return map[string]interface{}{"revocation_time": t}, nil
+	}
+
+	t := time.Now().Unix()
+	d["revocation_time"] = t
+
+	return map[string]interface{}{"revocation_time": t}, nil
+}
+
+func certKey(namespace, mount, serialNumber string) entryKey {
+	return entryKey{namespace: namespace, path: mount + "/certs/" + strings.Replace(strings.ToLower(serialNumber), ":", "-", -1)}
+}
+
+// formatSerial formats the given serial number the way Vault does, as colon
+// separated hex bytes.
+func formatSerial(serial *big.Int) string {
+	b := serial.Bytes()
+	parts := make([]string, len(b))
+	for i, v := range b {
+		parts[i] = fmt.Sprintf("%02x", v)
+	}
+
+	return strings.Join(parts, ":")
+}
@@ -0,0 +1,335 @@
+package vaultroletest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger/microloggertest"
+	vaultclient "github.com/hashicorp/vault/api"
+
+	"github.com/giantswarm/vaultrole"
+)
+
+const (
+	namespaceHeader = "X-Vault-Namespace"
+	tokenHeader     = "X-Vault-Token"
+)
+
+// Server is an in-memory stand-in for the Vault HTTP API serving the subset of
+// the logical API used by vaultrole. Requests are scoped to the namespace
+// given by the X-Vault-Namespace header. Secret engines have to be mounted
+// using Mount before they can be used, except for the system backend at sys.
+// Issuing, reading, listing and revoking certificates using PKI roles is
+// emulated with a CA generated per mount. Data written to PKI role paths is
+// normalised the way Vault does, so that list fields are returned as lists and
+// the TTL is returned in seconds.
+type Server struct {
+	server *httptest.Server
+
+	mutex  sync.Mutex
+	cas    map[entryKey]*certificateAuthority
+	data   map[entryKey]map[string]interface{}
+	mounts map[entryKey]bool
+	tokens map[string]bool
+}
+
+type entryKey struct {
+	namespace string
+	path      string
+}
+
+func NewServer() *Server {
+	s := &Server{
+		cas:    map[entryKey]*certificateAuthority{},
+		data:   map[entryKey]map[string]interface{}{},
+		mounts: map[entryKey]bool{},
+	}
+
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+
+	return s
+}
+
+// Client returns a Vault client configured to talk to the server.
+func (s *Server) Client() (*vaultclient.Client, error) {
+	c, err := vaultclient.NewClient(&vaultclient.Config{Address: s.server.URL})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	c.SetToken("test")
+
+	return c, nil
+}
+
+// NewVaultRole returns a VaultRole talking to the server. Fields left empty
+// in the given config default to the following values.
+//
+//	Logger              microloggertest.New()
+//	VaultClient         Client(), unless LogicalClient is set
+//	CommonNameFormat    "%s.g8s.gigantic.io", unless CommonNameTemplate is set
+func (s *Server) NewVaultRole(config vaultrole.Config) (*vaultrole.VaultRole, error) {
+	if config.Logger == nil {
+		config.Logger = microloggertest.New()
+	}
+	if config.VaultClient == nil && config.LogicalClient == nil {
+		c, err := s.Client()
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		config.VaultClient = c
+	}
+	if config.CommonNameFormat == "" && config.CommonNameTemplate == "" {
+		config.CommonNameFormat = "%s.g8s.gigantic.io"
+	}
+
+	r, err := vaultrole.New(config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return r, nil
+}
+
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Data returns the data stored at the given path within the given namespace
+// and whether it exists.
+func (s *Server) Data(namespace, path string) (map[string]interface{}, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	d, ok := s.data[entryKey{namespace: cleanNamespace(namespace), path: strings.Trim(path, "/")}]
+	return d, ok
+}
+
+// SetData stores the given data at the given path within the given namespace
+// as is. Other than writes issued through the HTTP API, the data is not
+// normalised, which allows to emulate roles written by older Vault versions.
+func (s *Server) SetData(namespace, path string, data map[string]interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.data[entryKey{namespace: cleanNamespace(namespace), path: strings.Trim(path, "/")}] = data
+}
+
+// SetTokens restricts access to requests using one of the given tokens.
+// Requests using other tokens are denied with status code 403. By default any
+// token is accepted.
+func (s *Server) SetTokens(tokens ...string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.tokens = map[string]bool{}
+	for _, t := range tokens {
+		s.tokens[t] = true
+	}
+}
+
+// Mount enables a secret engine at the given path within the given namespace,
+// e.g. pki-al9qy.
+func (s *Server) Mount(namespace, path string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.mounts[entryKey{namespace: cleanNamespace(namespace), path: strings.Trim(path, "/")}] = true
+}
+
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.tokens != nil && !s.tokens[r.Header.Get(tokenHeader)] {
+		writeJSON(w, http.StatusForbidden, map[string]interface{}{
+			"errors": []string{"permission denied"},
+		})
+		return
+	}
+
+	namespace := cleanNamespace(r.Header.Get(namespaceHeader))
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/"), "/")
+	mount := strings.SplitN(path, "/", 2)[0]
+
+	// The system backend is always mounted.
+	if mount != "sys" && !s.mounts[entryKey{namespace: namespace, path: mount}] {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{
+			"errors": []string{fmt.Sprintf("no handler for route '%s'", path)},
+		})
+		return
+	}
+
+	k := entryKey{namespace: namespace, path: path}
+
+	switch {
+	case r.Method == "LIST" || (r.Method == http.MethodGet && r.URL.Query().Get("list") == "true"):
+		keys := s.list(namespace, path)
+		if len(keys) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"data": map[string]interface{}{
+				"keys": keys,
+			},
+		})
+
+	case r.Method == http.MethodGet:
+		d, ok := s.data[k]
+		if isCertPath(path) {
+			d, ok = s.certificate(namespace, path)
+		}
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"data": d,
+		})
+
+	case r.Method == http.MethodPut || r.Method == http.MethodPost:
+		var d map[string]interface{}
+		err := json.NewDecoder(r.Body).Decode(&d)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"errors": []string{err.Error()},
+			})
+			return
+		}
+		if isIssuePath(path) {
+			d, err = s.issue(namespace, path, d)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"data": d,
+			})
+			return
+		}
+		if isRevokePath(path) {
+			d, err = s.revoke(namespace, path, d)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"data": d,
+			})
+			return
+		}
+		if isRolePath(path) {
+			d, err = normaliseRole(d)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+					"errors": []string{err.Error()},
+				})
+				return
+			}
+		}
+		s.data[k] = d
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == http.MethodDelete:
+		delete(s.data, k)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// list returns the names of the direct children of the given path. Names of
+// children having children themselves carry a trailing slash.
+func (s *Server) list(namespace, path string) []string {
+	prefix := path + "/"
+
+	seen := map[string]bool{}
+	for k := range s.data {
+		if k.namespace != namespace || !strings.HasPrefix(k.path, prefix) {
+			continue
+		}
+
+		name := strings.TrimPrefix(k.path, prefix)
+		if i := strings.Index(name, "/"); i >= 0 {
+			name = name[:i+1]
+		}
+		seen[name] = true
+	}
+
+	var keys []string
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func cleanNamespace(namespace string) string {
+	return strings.Trim(namespace, "/")
+}
+
+// isRolePath returns true for paths of the form <mount>/roles/<name>.
+func isRolePath(path string) bool {
+	parts := strings.Split(path, "/")
+	return len(parts) == 3 && parts[1] == "roles"
+}
+
+// normaliseRole converts the given role data the way the PKI backend of Vault
+// does. Comma separated lists are split into lists and the TTL is converted
+// into seconds.
+func normaliseRole(d map[string]interface{}) (map[string]interface{}, error) {
+	for _, k := range []string{"allowed_domains", "organization"} {
+		s, ok := d[k].(string)
+		if !ok {
+			continue
+		}
+
+		list := []interface{}{}
+		if s != "" {
+			for _, v := range strings.Split(s, ",") {
+				list = append(list, v)
+			}
+		}
+		d[k] = list
+	}
+
+	if s, ok := d["ttl"].(string); ok {
+		if s == "" {
+			d["ttl"] = 0
+		} else if n, err := strconv.Atoi(s); err == nil {
+			d["ttl"] = n
+		} else {
+			ttl, err := time.ParseDuration(s)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			d["ttl"] = int(ttl.Seconds())
+		}
+	}
+
+	return d, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
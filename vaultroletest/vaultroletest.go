@@ -13,10 +13,22 @@ func (r *VaultRoleTest) Create(config vaultrole.CreateConfig) error {
 	return nil
 }
 
+func (r *VaultRoleTest) Delete(config vaultrole.DeleteConfig) error {
+	return nil
+}
+
+func (r *VaultRoleTest) EnsureMany(configs []vaultrole.EnsureConfig) ([]vaultrole.EnsureResult, error) {
+	return nil, nil
+}
+
 func (r *VaultRoleTest) Exists(config vaultrole.ExistsConfig) (bool, error) {
 	return false, nil
 }
 
+func (r *VaultRoleTest) List(config vaultrole.ListConfig) ([]vaultrole.NamedRole, error) {
+	return nil, nil
+}
+
 func (r *VaultRoleTest) Search(config vaultrole.SearchConfig) (vaultrole.Role, error) {
 	return vaultrole.Role{}, nil
 }
@@ -12,4 +12,8 @@ func Test_VaultRoleTest_New(t *testing.T) {
 	if !ok {
 		t.Fatal("VaultRoleTest does not implement correct interface")
 	}
+	_, ok = interface{}(s).(vaultrole.ManageInterface)
+	if !ok {
+		t.Fatal("VaultRoleTest does not implement manage interface")
+	}
 }